@@ -1,12 +1,25 @@
 module github.com/foundation-framework/foundation
 
-go 1.17
+go 1.18
 
 require (
+	github.com/alicebob/miniredis/v2 v2.37.0
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 	github.com/google/uuid v1.3.0
 	github.com/gorilla/websocket v1.4.2
+	github.com/lib/pq v1.10.9
+	github.com/nats-io/nats.go v1.11.0
+	github.com/redis/go-redis/v9 v9.0.5
 	github.com/tinylib/msgp v1.1.6
+	google.golang.org/protobuf v1.28.1
 )
 
-require github.com/philhofer/fwd v1.1.1 // indirect
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/nats-io/nkeys v0.3.0 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/philhofer/fwd v1.1.1 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e // indirect
+)
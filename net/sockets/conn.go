@@ -10,6 +10,16 @@ type Conn interface {
 	// Accept method allows the connection to start reading messages
 	Accept()
 
+	// Handshake exchanges handshake frames with the other side of connection
+	// and returns handshake received from it
+	//
+	// Handshake is optional, but must be called before Accept on both sides
+	// Incompatible connection is closed with a reason and *HandshakeError returned
+	Handshake(ctx context.Context, local Handshake) (Handshake, error)
+
+	// Capabilities returns capabilities negotiated during handshake
+	Capabilities() []string
+
 	// LocalAddr returns local endpoint address
 	LocalAddr() net.Addr
 
@@ -23,6 +33,7 @@ type Conn interface {
 	BytesReceived() uint64
 
 	// SetEncoder sets encoder for a connection (MessagePack Encoder used by default)
+	// Use NewProtobufEncoder for proto.Message models
 	//
 	// You have to make sure that the same encoder is used
 	// on the other side of the connection
@@ -30,11 +41,40 @@ type Conn interface {
 
 	// Write writers new message to the connection
 	// Encoder used to encode message, use SetEncoder to change it
+	//
+	// Encoding failures are returned as *EncodeError
 	Write(topic string, data interface{}) error
 
 	// WriteWithReply does the same as Write but also accepts handler for reply
 	WriteWithReply(topic string, data interface{}, handler ReplyHandler) error
 
+	// WriteReliable does the same as Write but the message is kept
+	// until the other side acknowledges it (see SetReliable)
+	WriteReliable(topic string, data interface{}) error
+
+	// SetReliable sets state used by WriteReliable and to skip duplicated messages
+	//
+	// Pass the same state to a new connection after reconnect,
+	// all unacknowledged messages will be retransmitted immediately
+	// (so call it after Handshake, retransmitted messages break handshake frames)
+	// Messages failed to encode are not retransmitted anymore
+	//
+	// Nil state disables reliable mode
+	SetReliable(state *Reliable)
+
+	// WriteControl writes control message with a single value
+	// Topic must start with ControlPrefix
+	WriteControl(topic, value string) error
+
+	// OnControl sets callback for control messages not handled by the connection itself
+	//
+	// Only one callback allowed, next calls will replace callback
+	OnControl(func(topic, value string))
+
+	// WritePrepared does the same as Write but reuses encoded message
+	// between all connections using the same encoder type
+	WritePrepared(msg *PreparedMessage) error
+
 	// SetMessageHandlers sets handler for incoming message
 	// Encoder used to decode message, use SetEncoder to change it
 	//
@@ -45,6 +85,7 @@ type Conn interface {
 	RemoveMessageHandlers(handlers ...MessageHandler)
 
 	// OnError sets callback for non-critical connection errors
+	// (Decoding failures are passed as *DecodeError)
 	//
 	// Only one callback allowed, next calls will replace callback
 	OnError(func(err error))
@@ -0,0 +1,17 @@
+package sockets
+
+// ControlPrefix marks topics of control messages
+//
+// Control messages are handled by connections without message handlers,
+// a single string value is sent as message id
+const ControlPrefix = "$"
+
+const (
+	// TopicAck is a control topic used to acknowledge reliable messages
+	// (Value is an id of acknowledged message)
+	TopicAck = "$ack"
+
+	// TopicSession is a control topic used to send resume token of the session
+	// (Value is a token to be sent in SessionTokenHeader on reconnect)
+	TopicSession = "$session"
+)
@@ -0,0 +1,82 @@
+package sockets
+
+// DataChange represents change of the session data value
+type DataChange struct {
+	Key string
+
+	// Old is a previous value, Existed reports whether it was set
+	Old     interface{}
+	Existed bool
+
+	// Value is a new value, Deleted reports whether the value was removed
+	Value   interface{}
+	Deleted bool
+}
+
+// Key is a typed key of the session data
+//
+// Keys are identified by names, keys of different types
+// must not share the same name
+type Key[T any] struct {
+	name string
+}
+
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Get returns value of the key, false is returned when
+// the value is not set or has a different type
+func (k Key[T]) Get(s *Session) (T, bool) {
+	s.rmux.RLock()
+	defer s.rmux.RUnlock()
+
+	value, ok := s.data[k.name].(T)
+	return value, ok
+}
+
+func (k Key[T]) Set(s *Session, value T) {
+	s.SetData(k.name, value)
+}
+
+func (k Key[T]) Delete(s *Session) {
+	s.DropData(k.name)
+}
+
+// OnChange sets callback for changes of the key value in the session,
+// old and value are zero values when not set or deleted
+//
+// Multiple callback allowed
+func (k Key[T]) OnChange(s *Session, fn func(old, value T, change DataChange)) {
+	s.OnDataChange(func(change DataChange) {
+		if change.Key != k.name {
+			return
+		}
+
+		old, _ := change.Old.(T)
+		value, _ := change.Value.(T)
+
+		fn(old, value, change)
+	})
+}
+
+// OnDataChange sets callback for changes of the session data,
+// callbacks are called after the change in the changing goroutine
+//
+// Multiple callback allowed
+func (s *Session) OnDataChange(fn func(change DataChange)) {
+	s.rmux.Lock()
+	defer s.rmux.Unlock()
+
+	s.dataCb = append(s.dataCb, fn)
+}
+
+func callDataCb(callbacks []func(change DataChange), change DataChange) {
+	for _, fn := range callbacks {
+		fn(change)
+	}
+}
@@ -4,6 +4,9 @@ import "io"
 
 // Encoder describes connection encoding mechanism
 type Encoder interface {
+	// Name returns encoder name used to match encoders during handshake
+	Name() string
+
 	// ResetReader resets reader used to decode data
 	ResetReader(reader io.Reader)
 
@@ -14,18 +17,24 @@ type Encoder interface {
 	Flush() error
 
 	// ReadString reads a string from an underlying reader
-	// Any encoding errors must be returned
+	// Any decoding errors must be returned as *DecodeError
 	ReadString() (string, error)
 
 	// ReadData reads message data from underlying reader
-	// Any encoding returned by this method
+	// Any decoding errors must be returned as *DecodeError
+	// (ErrNotDecodable must be used for unsupported models)
+	//
+	// *RawMessage data must be filled with encoded bytes as is
 	ReadData(data interface{}) error
 
 	// WriteString writes a string to an underlying writer
-	// Method will panic on any encoding errors
+	// Any encoding errors must be returned
 	WriteString(content string) error
 
 	// WriteData writes message data to underlying writer
-	// Method will panic on any encoding errors
+	// Any encoding errors must be returned
+	// (ErrNotEncodable must be used for unsupported data)
+	//
+	// RawMessage (and *RawMessage) data must be written as is
 	WriteData(data interface{}) error
 }
@@ -3,13 +3,13 @@ package sockets
 import (
 	"io"
 
-	"github.com/foundation-framework/foundation/errors"
 	"github.com/tinylib/msgp/msgp"
 )
 
 type msgpackEncoder struct {
-	reader *msgp.Reader
-	writer *msgp.Writer
+	counter readerCounter
+	reader  *msgp.Reader
+	writer  *msgp.Writer
 }
 
 func NewMsgpackEncoder() Encoder {
@@ -19,8 +19,13 @@ func NewMsgpackEncoder() Encoder {
 	}
 }
 
+func (e *msgpackEncoder) Name() string {
+	return "msgpack"
+}
+
 func (e *msgpackEncoder) ResetReader(reader io.Reader) {
-	e.reader.Reset(reader)
+	e.counter.Reset(reader)
+	e.reader.Reset(&e.counter)
 }
 
 func (e *msgpackEncoder) ResetWriter(writer io.Writer) {
@@ -32,18 +37,32 @@ func (e *msgpackEncoder) Flush() error {
 }
 
 func (e *msgpackEncoder) ReadString() (string, error) {
-	return e.reader.ReadString()
+	content, err := e.reader.ReadString()
+	if err != nil {
+		return "", e.decodeError(err)
+	}
+
+	return content, nil
 }
 
 func (e *msgpackEncoder) ReadData(data interface{}) error {
+	if raw, ok := data.(*RawMessage); ok {
+		// Reading next object without decoding
+		if err := (*msgp.Raw)(raw).DecodeMsg(e.reader); err != nil {
+			return e.decodeError(err)
+		}
+
+		return nil
+	}
+
 	decodable, ok := data.(msgp.Decodable)
 	if !ok {
-		panic("sockets: data is not msgp.Decodable")
+		return e.decodeError(ErrNotDecodable)
 	}
 
 	if err := decodable.DecodeMsg(e.reader); err != nil {
 		// We must return any Decode errors, to properly handle them
-		return err
+		return e.decodeError(err)
 	}
 
 	return nil
@@ -55,16 +74,25 @@ func (e *msgpackEncoder) WriteString(topic string) error {
 }
 
 func (e *msgpackEncoder) WriteData(data interface{}) error {
-	// Any encoding errors must panic to prevent wrong usage
+	switch raw := data.(type) {
+	case RawMessage:
+		return msgp.Raw(raw).EncodeMsg(e.writer)
+	case *RawMessage:
+		return msgp.Raw(*raw).EncodeMsg(e.writer)
+	}
+
 	encodable, ok := data.(msgp.Encodable)
 	if !ok {
-		panic("sockets: data is not msgp.Encodable")
+		return ErrNotEncodable
 	}
 
-	err := encodable.EncodeMsg(e.writer)
-	if _, ok := err.(msgp.Error); ok {
-		errors.Panicf("sockets: unexpected encoding error: %s", err.Error())
-	}
+	return encodable.EncodeMsg(e.writer)
+}
 
-	return err
+func (e *msgpackEncoder) decodeError(err error) error {
+	return &DecodeError{
+		// Buffered bytes are read from the message, but not decoded yet
+		Offset: e.counter.Count() - e.reader.Buffered(),
+		Err:    err,
+	}
 }
@@ -0,0 +1,150 @@
+package sockets
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/units"
+	"google.golang.org/protobuf/proto"
+)
+
+// protobufMaxFieldSize limits size of a single length-delimited field
+var protobufMaxFieldSize = units.Megabyte * 64
+
+type protobufEncoder struct {
+	counter readerCounter
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+}
+
+// NewProtobufEncoder creates Encoder that accepts proto.Message models
+//
+// Every message field (id, topic and payload) is written
+// as varint length followed by field bytes
+func NewProtobufEncoder() Encoder {
+	return &protobufEncoder{
+		reader: bufio.NewReader(nil),
+		writer: bufio.NewWriter(nil),
+	}
+}
+
+func (e *protobufEncoder) Name() string {
+	return "protobuf"
+}
+
+func (e *protobufEncoder) ResetReader(reader io.Reader) {
+	e.counter.Reset(reader)
+	e.reader.Reset(&e.counter)
+}
+
+func (e *protobufEncoder) ResetWriter(writer io.Writer) {
+	e.writer.Reset(writer)
+}
+
+func (e *protobufEncoder) Flush() error {
+	return e.writer.Flush()
+}
+
+func (e *protobufEncoder) ReadString() (string, error) {
+	content, err := e.readBytes()
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+func (e *protobufEncoder) ReadData(data interface{}) error {
+	if raw, ok := data.(*RawMessage); ok {
+		content, err := e.readBytes()
+		if err != nil {
+			return err
+		}
+
+		*raw = content
+		return nil
+	}
+
+	message, ok := data.(proto.Message)
+	if !ok {
+		return e.decodeError(ErrNotDecodable)
+	}
+
+	content, err := e.readBytes()
+	if err != nil {
+		return err
+	}
+
+	if err := proto.Unmarshal(content, message); err != nil {
+		// Unmarshal fails after the whole field is read
+		return e.decodeError(err)
+	}
+
+	return nil
+}
+
+func (e *protobufEncoder) WriteString(content string) error {
+	return e.writeBytes([]byte(content))
+}
+
+func (e *protobufEncoder) WriteData(data interface{}) error {
+	switch raw := data.(type) {
+	case RawMessage:
+		return e.writeBytes(raw)
+	case *RawMessage:
+		return e.writeBytes(*raw)
+	}
+
+	message, ok := data.(proto.Message)
+	if !ok {
+		return ErrNotEncodable
+	}
+
+	content, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return e.writeBytes(content)
+}
+
+// readBytes reads length-delimited field, any errors are returned as *DecodeError
+func (e *protobufEncoder) readBytes() ([]byte, error) {
+	size, err := binary.ReadUvarint(e.reader)
+	if err != nil {
+		return nil, e.decodeError(err)
+	}
+
+	if size > protobufMaxFieldSize {
+		return nil, e.decodeError(errors.Newf("sockets: field size %d exceeds limit", size))
+	}
+
+	content := make([]byte, size)
+	if _, err := io.ReadFull(e.reader, content); err != nil {
+		return nil, e.decodeError(err)
+	}
+
+	return content, nil
+}
+
+func (e *protobufEncoder) writeBytes(content []byte) error {
+	var size [binary.MaxVarintLen64]byte
+
+	n := binary.PutUvarint(size[:], uint64(len(content)))
+	if _, err := e.writer.Write(size[:n]); err != nil {
+		return err
+	}
+
+	_, err := e.writer.Write(content)
+	return err
+}
+
+func (e *protobufEncoder) decodeError(err error) error {
+	return &DecodeError{
+		// Buffered bytes are read from the message, but not decoded yet
+		Offset: e.counter.Count() - e.reader.Buffered(),
+		Err:    err,
+	}
+}
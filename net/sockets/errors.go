@@ -0,0 +1,52 @@
+package sockets
+
+import (
+	"fmt"
+
+	"github.com/foundation-framework/foundation/errors"
+)
+
+var (
+	// ErrNotEncodable is returned when data type is not supported by the encoder
+	ErrNotEncodable = errors.New("sockets: data is not encodable")
+
+	// ErrNotDecodable is returned when model type is not supported by the encoder
+	ErrNotDecodable = errors.New("sockets: model is not decodable")
+)
+
+// EncodeError represents failure to encode outgoing message
+type EncodeError struct {
+	Topic string
+	Err   error
+}
+
+func (e *EncodeError) Error() string {
+	return fmt.Sprintf("sockets: failed to encode \"%s\" message: %v", e.Topic, e.Err)
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError represents failure to decode incoming message
+type DecodeError struct {
+	// Topic is empty when message topic itself can't be decoded
+	Topic string
+
+	// Offset is a position in the message where decoding failed
+	Offset int
+
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Topic == "" {
+		return fmt.Sprintf("sockets: failed to decode message at %d: %v", e.Offset, e.Err)
+	}
+
+	return fmt.Sprintf("sockets: failed to decode \"%s\" message at %d: %v", e.Topic, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
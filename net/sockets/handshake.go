@@ -0,0 +1,66 @@
+package sockets
+
+import (
+	"fmt"
+)
+
+// ProtocolVersion is a version of the connection wire format
+const ProtocolVersion = 1
+
+// Handshake describes connection parameters exchanged by both sides
+// in the first frame of the connection
+type Handshake struct {
+	// Protocol is a wire format version (ProtocolVersion used if empty)
+	Protocol int `json:"protocol"`
+
+	// Encoder is a name of the connection encoder (filled automatically)
+	Encoder string `json:"encoder"`
+
+	// Name and Version describe the side of connection (e.g. client application)
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// Capabilities lists optional features supported by the side
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// HandshakeError represents rejected handshake
+//
+// Reason is also used as a close reason of the connection
+type HandshakeError struct {
+	Reason string
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("sockets: handshake rejected: %s", e.Reason)
+}
+
+// Negotiate checks that remote handshake is compatible with local one
+// and returns capabilities supported by both sides
+func Negotiate(local, remote Handshake) ([]string, error) {
+	if remote.Protocol != local.Protocol {
+		return nil, &HandshakeError{
+			Reason: fmt.Sprintf("unsupported protocol version %d (expected %d)", remote.Protocol, local.Protocol),
+		}
+	}
+
+	if remote.Encoder != local.Encoder {
+		return nil, &HandshakeError{
+			Reason: fmt.Sprintf("unsupported encoder \"%s\" (expected \"%s\")", remote.Encoder, local.Encoder),
+		}
+	}
+
+	supported := map[string]struct{}{}
+	for _, capability := range remote.Capabilities {
+		supported[capability] = struct{}{}
+	}
+
+	capabilities := []string{}
+	for _, capability := range local.Capabilities {
+		if _, ok := supported[capability]; ok {
+			capabilities = append(capabilities, capability)
+		}
+	}
+
+	return capabilities, nil
+}
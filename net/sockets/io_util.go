@@ -0,0 +1,24 @@
+package sockets
+
+import "io"
+
+type readerCounter struct {
+	reader io.Reader
+	count  int
+}
+
+func (r *readerCounter) Read(data []byte) (int, error) {
+	n, err := r.reader.Read(data)
+	r.count += n
+
+	return n, err
+}
+
+func (r *readerCounter) Reset(reader io.Reader) {
+	r.reader = reader
+	r.count = 0
+}
+
+func (r *readerCounter) Count() int {
+	return r.count
+}
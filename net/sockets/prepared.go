@@ -0,0 +1,67 @@
+package sockets
+
+import (
+	"sync"
+
+	"github.com/foundation-framework/foundation/rand"
+)
+
+// PreparedMessage represents message encoded once and written to many connections
+//
+// Message is encoded once for each encoder type, so connections
+// with different encoders can share the same PreparedMessage
+type PreparedMessage struct {
+	id    string
+	topic string
+	data  interface{}
+
+	frames      map[interface{}]interface{}
+	framesMutex sync.Mutex
+}
+
+func NewPreparedMessage(topic string, data interface{}) *PreparedMessage {
+	return newPreparedMessage(rand.UUID(), topic, data)
+}
+
+func newPreparedMessage(id, topic string, data interface{}) *PreparedMessage {
+	return &PreparedMessage{
+		id:     id,
+		topic:  topic,
+		data:   data,
+		frames: map[interface{}]interface{}{},
+	}
+}
+
+// ID returns message id shared between all receivers
+func (m *PreparedMessage) ID() string {
+	return m.id
+}
+
+func (m *PreparedMessage) Topic() string {
+	return m.topic
+}
+
+func (m *PreparedMessage) Data() interface{} {
+	return m.data
+}
+
+// Frame returns frame stored by the key, fn is used to encode missing frame
+// (This method used in Conn implementation)
+//
+// Errors returned by fn are not stored
+func (m *PreparedMessage) Frame(key interface{}, fn func() (interface{}, error)) (interface{}, error) {
+	m.framesMutex.Lock()
+	defer m.framesMutex.Unlock()
+
+	if frame, ok := m.frames[key]; ok {
+		return frame, nil
+	}
+
+	frame, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	m.frames[key] = frame
+	return frame, nil
+}
@@ -0,0 +1,8 @@
+package sockets
+
+// RawMessage represents already encoded message data
+//
+// Encoders write RawMessage as is and read data into *RawMessage
+// without decoding, so messages can be forwarded without knowing their model
+// (Make sure the same encoder is used on both sides of the forwarding)
+type RawMessage []byte
@@ -0,0 +1,129 @@
+package sockets
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/rand"
+)
+
+// reliableIDPrefix marks messages that must be acknowledged
+const reliableIDPrefix = "r:"
+
+// reliableWindow is a number of received message ids remembered to skip duplicates
+var reliableWindow = 4096
+
+var (
+	// ErrReliableDisabled is returned by WriteReliable when reliable state is not set
+	ErrReliableDisabled = errors.New("sockets: reliable state is not set")
+
+	// ErrReliableOverflow is returned when too many messages are not acknowledged
+	ErrReliableOverflow = errors.New("sockets: too many unacknowledged messages")
+)
+
+// IsReliableID reports whether message with the id must be acknowledged
+func IsReliableID(id string) bool {
+	return strings.HasPrefix(id, reliableIDPrefix)
+}
+
+// ReliableMessage represents outbound message waiting for acknowledgement
+type ReliableMessage struct {
+	ID    string
+	Topic string
+	Data  interface{}
+}
+
+// Reliable keeps state of at-least-once delivery
+//
+// The same state must be passed to a new connection after reconnect
+// to retransmit unacknowledged messages and to skip duplicated ones
+type Reliable struct {
+	origin   string
+	sequence uint64
+	limit    int
+	pending  []ReliableMessage
+
+	received      map[string]struct{}
+	receivedOrder []string
+
+	mutex sync.Mutex
+}
+
+// NewReliable creates reliable state, limit restricts
+// a number of unacknowledged messages (zero means no limit)
+func NewReliable(limit int) *Reliable {
+	return &Reliable{
+		// Origin makes ids unique between different states
+		origin:   rand.Hex(8),
+		limit:    limit,
+		received: map[string]struct{}{},
+	}
+}
+
+// Add numbers new outbound message and keeps it until acknowledgement
+// (This method used in Conn implementation)
+func (r *Reliable) Add(topic string, data interface{}) (ReliableMessage, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.limit > 0 && len(r.pending) >= r.limit {
+		return ReliableMessage{}, ErrReliableOverflow
+	}
+
+	r.sequence += 1
+
+	msg := ReliableMessage{
+		ID:    reliableIDPrefix + r.origin + ":" + strconv.FormatUint(r.sequence, 10),
+		Topic: topic,
+		Data:  data,
+	}
+
+	r.pending = append(r.pending, msg)
+	return msg, nil
+}
+
+// Ack removes acknowledged message
+// (This method used in Conn implementation)
+func (r *Reliable) Ack(id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, msg := range r.pending {
+		if msg.ID == id {
+			r.pending = append(r.pending[:i], r.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// Pending returns unacknowledged messages in order they were added
+func (r *Reliable) Pending() []ReliableMessage {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]ReliableMessage{}, r.pending...)
+}
+
+// Receive remembers incoming message id and reports whether it is a duplicate
+// (This method used in Conn implementation)
+func (r *Reliable) Receive(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.received[id]; ok {
+		return true
+	}
+
+	r.received[id] = struct{}{}
+	r.receivedOrder = append(r.receivedOrder, id)
+
+	// Forgetting the oldest ids
+	if len(r.receivedOrder) > reliableWindow {
+		delete(r.received, r.receivedOrder[0])
+		r.receivedOrder = r.receivedOrder[1:]
+	}
+
+	return false
+}
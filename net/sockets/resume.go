@@ -0,0 +1,203 @@
+package sockets
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/rand"
+	"github.com/foundation-framework/foundation/session"
+)
+
+// SessionTokenHeader is a header used to send resume token on reconnect
+const SessionTokenHeader = "X-Session-Token"
+
+// ErrSessionExpired is passed to close callbacks of the session not resumed in time
+var ErrSessionExpired = errors.New("sockets: session is not resumed in time")
+
+// Resumer keeps disconnected sessions to resume them after reconnect
+//
+// Resumed session keeps its id, data and rooms, broadcast messages
+// received after disconnect are replayed to a new connection
+type Resumer struct {
+	hub   *session.Hub
+	grace time.Duration
+	limit int
+
+	sessions      map[string]*Session
+	sessionsMutex sync.Mutex
+}
+
+// NewResumer creates Resumer, disconnected sessions are kept during grace period
+//
+// Limit restricts a number of messages buffered for disconnected session
+// (zero means no limit)
+func NewResumer(hub *session.Hub, grace time.Duration, limit int) *Resumer {
+	return &Resumer{
+		hub:      hub,
+		grace:    grace,
+		limit:    limit,
+		sessions: map[string]*Session{},
+	}
+}
+
+// Session resumes session by the token (see SessionTokenHeader) or creates a new one
+// and reports whether the session was resumed
+//
+// New resume token is sent to the other side with TopicSession control message
+// and buffered messages are replayed immediately, so call it after Handshake
+func (r *Resumer) Session(conn Conn, token string) (*Session, bool) {
+	r.sessionsMutex.Lock()
+	existing := r.sessions[token]
+
+	// Every token can be used only once
+	delete(r.sessions, token)
+	r.sessionsMutex.Unlock()
+
+	if existing != nil && existing.resume(conn) {
+		r.issue(existing)
+		return existing, true
+	}
+
+	result := newSession(r.hub, conn, r)
+
+	r.issue(result)
+	return result, false
+}
+
+// issue issues new resume token for the session
+func (r *Resumer) issue(s *Session) {
+	token := rand.Hex(32)
+
+	r.sessionsMutex.Lock()
+	r.sessions[token] = s
+	r.sessionsMutex.Unlock()
+
+	s.connMutex.Lock()
+	s.token = token
+	s.connMutex.Unlock()
+
+	// Ignoring any errors, connection closure is handled by the session
+	_ = s.WriteControl(TopicSession, token)
+}
+
+func (r *Resumer) remove(s *Session) {
+	r.sessionsMutex.Lock()
+	defer r.sessionsMutex.Unlock()
+
+	if r.sessions[s.token] == s {
+		delete(r.sessions, s.token)
+	}
+}
+
+// Token returns current resume token of the session
+// (empty for sessions not created by Resumer)
+func (s *Session) Token() string {
+	s.connMutex.RLock()
+	defer s.connMutex.RUnlock()
+
+	return s.token
+}
+
+// detach waits for the session resumption
+// (connMutex must be locked)
+func (s *Session) detach() {
+	s.detached = true
+	s.expiry = time.AfterFunc(s.resumer.grace, func() {
+		s.expire(ErrSessionExpired)
+	})
+}
+
+// bufferMessage keeps message to replay it after resumption
+// (connMutex must be locked)
+func (s *Session) bufferMessage(msg *session.Message) {
+	s.buffer = append(s.buffer, msg)
+
+	// Dropping the oldest messages
+	if limit := s.resumer.limit; limit > 0 && len(s.buffer) > limit {
+		s.buffer = s.buffer[len(s.buffer)-limit:]
+	}
+}
+
+// expire ends detached session
+func (s *Session) expire(err error) {
+	s.connMutex.Lock()
+
+	if !s.detached {
+		// Session was resumed in time
+		s.connMutex.Unlock()
+		return
+	}
+
+	// Session is not detached anymore, so it is ended only once
+	// (by repeated Close or by expiry timer racing with Close)
+	s.expiry.Stop()
+	s.detached = false
+	s.closing = true
+	s.buffer = nil
+	s.connMutex.Unlock()
+
+	s.end(err)
+}
+
+// resume attaches new connection to the session
+func (s *Session) resume(conn Conn) bool {
+	s.connMutex.Lock()
+
+	if s.closing {
+		s.connMutex.Unlock()
+		return false
+	}
+
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+
+	previous, detached := s.conn, s.detached
+	s.conn, s.detached = conn, false
+
+	buffer := s.buffer
+	s.buffer = nil
+
+	handlers := make([]MessageHandler, 0, len(s.handlers))
+	for _, handler := range s.handlers {
+		handlers = append(handlers, handler)
+	}
+
+	encoder, reliable := s.encoder, s.reliable
+	errorCb, fatalCb, controlCb := s.errorCb, s.fatalCb, s.controlCb
+	s.connMutex.Unlock()
+
+	if !detached {
+		// Other side reconnected before the closure was detected
+		_ = previous.Close(context.Background())
+	}
+
+	if encoder != nil {
+		conn.SetEncoder(encoder)
+	}
+	if errorCb != nil {
+		conn.OnError(errorCb)
+	}
+	if fatalCb != nil {
+		conn.OnFatal(fatalCb)
+	}
+	if controlCb != nil {
+		conn.OnControl(controlCb)
+	}
+
+	conn.SetMessageHandlers(handlers...)
+	s.watchClose(conn)
+
+	if reliable != nil {
+		// Retransmitting unacknowledged messages
+		conn.SetReliable(reliable)
+	}
+
+	for _, msg := range buffer {
+		s.reportWriteError(s.writeMessage(msg))
+	}
+
+	return true
+}
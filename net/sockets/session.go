@@ -3,41 +3,132 @@ package sockets
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/foundation-framework/foundation/errors"
 	"github.com/foundation-framework/foundation/rand"
 	"github.com/foundation-framework/foundation/session"
 )
 
-type Session struct {
-	Conn
-
-	hub   *session.Hub
-	rooms map[string]struct{}
+type preparedMessageKey struct{}
 
-	id   string
-	data map[string]interface{}
-	rmux sync.RWMutex
+// Session represents a connection with its own identity, data and rooms
+//
+// Session implements Conn by delegating calls to the current connection,
+// so resumed session keeps handlers and callbacks set before reconnect
+//
+// Closed session automatically leaves all rooms and unregisters from the hub
+type Session struct {
+	hub *session.Hub
+
+	// Context lives while the session (see Context)
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	id     string
+	data   map[string]interface{}
+	dataCb []func(change DataChange)
+	rmux   sync.RWMutex
+
+	conn      Conn
+	connMutex sync.RWMutex
+
+	// Resumption state (see Resumer)
+	resumer  *Resumer
+	token    string
+	detached bool
+	closing  bool
+	buffer   []*session.Message
+	expiry   *time.Timer
+
+	// Connection settings applied to a resumed connection
+	encoder   Encoder
+	reliable  *Reliable
+	handlers  map[string]MessageHandler
+	errorCb   func(err error)
+	fatalCb   func(topic string, data interface{}, msg interface{})
+	controlCb func(topic, value string)
+	closeCb   []func(err error)
 }
 
+// NewSession creates session that is not resumable (see Resumer)
 func NewSession(hub *session.Hub, conn Conn) *Session {
-	return &Session{
-		Conn: conn,
+	return newSession(hub, conn, nil)
+}
 
-		hub:   hub,
-		rooms: map[string]struct{}{},
+func newSession(hub *session.Hub, conn Conn, resumer *Resumer) *Session {
+	result := &Session{
+		hub: hub,
 
 		id:   rand.UUID(),
 		data: map[string]interface{}{},
+
+		conn:     conn,
+		resumer:  resumer,
+		handlers: map[string]MessageHandler{},
+		closeCb:  []func(err error){},
 	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result.ctx = PackSession(ctx, result)
+	result.cancel = cancel
+
+	hub.Register(result)
+	result.watchClose(conn)
+
+	return result
 }
 
 func (s *Session) ID() string {
 	return s.id
 }
 
-func (s *Session) ServeBroadcast(topic string, data interface{}) {
-	// Ignoring any errors
-	_ = s.Write(topic, data)
+// Conn returns current connection of the session
+func (s *Session) Conn() Conn {
+	s.connMutex.RLock()
+	defer s.connMutex.RUnlock()
+
+	return s.conn
+}
+
+func (s *Session) ServeBroadcast(msg *session.Message) {
+	s.connMutex.Lock()
+	if s.detached {
+		// Message will be replayed after resumption
+		s.bufferMessage(msg)
+		s.connMutex.Unlock()
+
+		return
+	}
+	s.connMutex.Unlock()
+
+	s.reportWriteError(s.writeMessage(msg))
+}
+
+func (s *Session) writeMessage(msg *session.Message) error {
+	prepared := msg.Shared(preparedMessageKey{}, func() interface{} {
+		// Message id is kept to let clients refer to it (e.g. in history queries)
+		return newPreparedMessage(msg.ID, msg.Topic, msg.Data)
+	})
+
+	return s.WritePrepared(prepared.(*PreparedMessage))
+}
+
+// reportWriteError passes encoding failures to OnError,
+// other write errors mean closed connection and are ignored
+func (s *Session) reportWriteError(err error) {
+	var encodeErr *EncodeError
+	if !errors.As(err, &encodeErr) {
+		return
+	}
+
+	s.connMutex.RLock()
+	errorCb := s.errorCb
+	s.connMutex.RUnlock()
+
+	if errorCb != nil {
+		errorCb(err)
+	}
 }
 
 func (s *Session) GetData(key string) interface{} {
@@ -47,44 +138,97 @@ func (s *Session) GetData(key string) interface{} {
 	return s.data[key]
 }
 
+// SetData stores data by the key and notifies data observers (see OnDataChange)
 func (s *Session) SetData(key string, data interface{}) {
 	s.rmux.Lock()
-	defer s.rmux.Unlock()
-
+	old, existed := s.data[key]
 	s.data[key] = data
+	callbacks := s.dataCb
+	s.rmux.Unlock()
+
+	callDataCb(callbacks, DataChange{
+		Key:     key,
+		Old:     old,
+		Value:   data,
+		Existed: existed,
+	})
 }
 
+// DropData removes data by the key and notifies data observers (see OnDataChange)
 func (s *Session) DropData(key string) {
 	s.rmux.Lock()
-	defer s.rmux.Unlock()
-
+	old, existed := s.data[key]
 	delete(s.data, key)
-}
+	callbacks := s.dataCb
+	s.rmux.Unlock()
 
-func (s *Session) Join(room string) {
-	if s.hub.Join(s, room) {
-		s.rooms[room] = struct{}{}
+	if !existed {
+		return
 	}
+
+	callDataCb(callbacks, DataChange{
+		Key:     key,
+		Old:     old,
+		Existed: true,
+		Deleted: true,
+	})
+}
+
+// Join joins the room, room membership is stored by the hub,
+// so it is safe to change it from concurrent handlers
+//
+// session.ErrRoomFull is returned when the room reached its members limit
+// and *session.AccessError when hub authorizer denied joining
+func (s *Session) Join(room string) error {
+	_, err := s.hub.Join(s, room)
+	return err
+}
+
+// JoinWithHistory joins the room and replays up to limit last messages of it
+// (see session.Hub.JoinWithHistory)
+func (s *Session) JoinWithHistory(room string, limit int) error {
+	_, err := s.hub.JoinWithHistory(s, room, limit)
+	return err
+}
+
+// ReplaySince replays messages of the room written after the message with id,
+// session.ErrHistoryNotFound is returned when the message is not in the history
+// and *session.AccessError when the session is not a member of the room
+func (s *Session) ReplaySince(room, id string) error {
+	return s.hub.ReplaySince(s, room, id)
 }
 
 func (s *Session) Leave(room string) {
-	if s.hub.Leave(s, room) {
-		delete(s.rooms, room)
-	}
+	s.hub.Leave(s, room)
 }
 
 func (s *Session) LeaveAll() {
-	for room := range s.rooms {
+	for _, room := range s.hub.RoomsOf(s) {
 		s.hub.Leave(s, room)
 	}
+}
+
+// Rooms returns sorted snapshot of rooms joined by the session
+func (s *Session) Rooms() []string {
+	return s.hub.RoomsOf(s)
+}
 
-	s.rooms = map[string]struct{}{}
+// InRoom reports whether the session is a member of the room
+func (s *Session) InRoom(room string) bool {
+	return s.hub.InRoom(s, room)
 }
 
-func (s *Session) Broadcast(room string, topic string, data interface{}) {
-	s.hub.Broadcast(s, room, topic, data)
+// Broadcast broadcasts message to the room members except the session,
+// *session.AccessError is returned when hub authorizer denied broadcasting
+func (s *Session) Broadcast(room string, topic string, data interface{}) error {
+	return s.hub.Broadcast(s, room, topic, data)
 }
 
+// Context returns context carrying the session (see UnpackSession),
+// it is cancelled when the session ends: its connection is closed
+// and the session is not resumable or is not resumed in time
+//
+// Use OnClose to get the close error
 func (s *Session) Context() context.Context {
-	return PackSession(context.TODO(), s)
+	return s.ctx
 }
@@ -0,0 +1,191 @@
+package sockets
+
+import (
+	"context"
+	"net"
+)
+
+func (s *Session) Accept() {
+	s.Conn().Accept()
+}
+
+func (s *Session) Handshake(ctx context.Context, local Handshake) (Handshake, error) {
+	return s.Conn().Handshake(ctx, local)
+}
+
+func (s *Session) Capabilities() []string {
+	return s.Conn().Capabilities()
+}
+
+func (s *Session) LocalAddr() net.Addr {
+	return s.Conn().LocalAddr()
+}
+
+func (s *Session) RemoteAddr() net.Addr {
+	return s.Conn().RemoteAddr()
+}
+
+func (s *Session) BytesSent() uint64 {
+	return s.Conn().BytesSent()
+}
+
+func (s *Session) BytesReceived() uint64 {
+	return s.Conn().BytesReceived()
+}
+
+func (s *Session) SetEncoder(encoder Encoder) {
+	s.connMutex.Lock()
+	s.encoder = encoder
+	s.connMutex.Unlock()
+
+	s.Conn().SetEncoder(encoder)
+}
+
+func (s *Session) Write(topic string, data interface{}) error {
+	return s.Conn().Write(topic, data)
+}
+
+func (s *Session) WriteWithReply(topic string, data interface{}, handler ReplyHandler) error {
+	return s.Conn().WriteWithReply(topic, data, handler)
+}
+
+func (s *Session) WriteReliable(topic string, data interface{}) error {
+	return s.Conn().WriteReliable(topic, data)
+}
+
+func (s *Session) SetReliable(state *Reliable) {
+	s.connMutex.Lock()
+	s.reliable = state
+	s.connMutex.Unlock()
+
+	s.Conn().SetReliable(state)
+}
+
+func (s *Session) WriteControl(topic, value string) error {
+	return s.Conn().WriteControl(topic, value)
+}
+
+func (s *Session) OnControl(fn func(topic, value string)) {
+	s.connMutex.Lock()
+	s.controlCb = fn
+	s.connMutex.Unlock()
+
+	s.Conn().OnControl(fn)
+}
+
+func (s *Session) WritePrepared(msg *PreparedMessage) error {
+	return s.Conn().WritePrepared(msg)
+}
+
+func (s *Session) SetMessageHandlers(handlers ...MessageHandler) {
+	s.connMutex.Lock()
+	for _, handler := range handlers {
+		s.handlers[handler.Topic()] = handler
+	}
+	s.connMutex.Unlock()
+
+	s.Conn().SetMessageHandlers(handlers...)
+}
+
+func (s *Session) RemoveMessageHandlers(handlers ...MessageHandler) {
+	s.connMutex.Lock()
+	for _, handler := range handlers {
+		delete(s.handlers, handler.Topic())
+	}
+	s.connMutex.Unlock()
+
+	s.Conn().RemoveMessageHandlers(handlers...)
+}
+
+// OnError sets callback for non-critical errors of all session connections
+// (Broadcast encoding failures are passed as *EncodeError)
+func (s *Session) OnError(fn func(err error)) {
+	s.connMutex.Lock()
+	s.errorCb = fn
+	s.connMutex.Unlock()
+
+	s.Conn().OnError(fn)
+}
+
+func (s *Session) OnFatal(fn func(topic string, data interface{}, msg interface{})) {
+	s.connMutex.Lock()
+	s.fatalCb = fn
+	s.connMutex.Unlock()
+
+	s.Conn().OnFatal(fn)
+}
+
+// OnClose sets callback for the session closure
+//
+// Resumable session is closed only when it is not resumed in time
+// Multiple callback allowed
+func (s *Session) OnClose(fn func(err error)) {
+	s.connMutex.Lock()
+	defer s.connMutex.Unlock()
+
+	s.closeCb = append(s.closeCb, fn)
+}
+
+// Close closes the session, closed session can't be resumed
+func (s *Session) Close(ctx context.Context) error {
+	s.connMutex.Lock()
+	if s.closing {
+		// Session is already closing or ended
+		s.connMutex.Unlock()
+		return nil
+	}
+
+	s.closing = true
+	detached := s.detached
+	s.connMutex.Unlock()
+
+	if detached {
+		// Connection is already closed, ending the session right now
+		s.expire(nil)
+		return nil
+	}
+
+	return s.Conn().Close(ctx)
+}
+
+// watchClose makes the session handle closure of the connection
+func (s *Session) watchClose(conn Conn) {
+	conn.OnClose(func(err error) {
+		s.connMutex.Lock()
+
+		if s.conn != conn {
+			// Connection was replaced by resumption
+			s.connMutex.Unlock()
+			return
+		}
+
+		if s.resumer != nil && !s.closing {
+			s.detach()
+			s.connMutex.Unlock()
+
+			return
+		}
+
+		s.connMutex.Unlock()
+		s.end(err)
+	})
+}
+
+// end ends the session, leaves all rooms and calls close callbacks
+func (s *Session) end(err error) {
+	if s.resumer != nil {
+		s.resumer.remove(s)
+	}
+
+	s.LeaveAll()
+	s.hub.Unregister(s)
+	s.cancel()
+
+	s.connMutex.RLock()
+	callbacks := s.closeCb
+	s.connMutex.RUnlock()
+
+	for _, fn := range callbacks {
+		fn(err)
+	}
+}
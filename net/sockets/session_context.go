@@ -4,28 +4,41 @@ import (
 	"context"
 )
 
-const (
-	SessionContextKey = "session"
-)
+// SessionKey is a context key of the session, keys are unique
+// even if created with the same name, so several sessions can be
+// stored in one context without collisions (e.g. by relays)
+type SessionKey struct {
+	name string
+}
 
-func PackSession(ctx context.Context, session *Session) context.Context {
-	return context.WithValue(ctx, SessionContextKey, session)
+// sessionKey is a key used by PackSession and Session.Context
+var sessionKey = NewSessionKey("session")
+
+func NewSessionKey(name string) *SessionKey {
+	return &SessionKey{name: name}
 }
 
-func UnpackSession(ctx context.Context) *Session {
-	session, ok := ctx.Value(SessionContextKey).(*Session)
-	if !ok {
-		return nil
-	}
+func (k *SessionKey) String() string {
+	return "sockets.SessionKey(" + k.name + ")"
+}
 
-	return session
+func (k *SessionKey) Pack(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, k, session)
 }
 
-func UnpackSessionNamed(ctx context.Context, name string) *Session {
-	session, ok := ctx.Value(name).(*Session)
+func (k *SessionKey) Unpack(ctx context.Context) *Session {
+	session, ok := ctx.Value(k).(*Session)
 	if !ok {
 		return nil
 	}
 
 	return session
 }
+
+func PackSession(ctx context.Context, session *Session) context.Context {
+	return sessionKey.Pack(ctx, session)
+}
+
+func UnpackSession(ctx context.Context) *Session {
+	return sessionKey.Unpack(ctx)
+}
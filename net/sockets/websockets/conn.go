@@ -1,12 +1,15 @@
 package websockets
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"reflect"
 	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,10 +24,26 @@ var (
 	pingCheckTimeout = time.Second * 4
 )
 
+type preparedFrameKey struct {
+	encoder reflect.Type
+}
+
+type preparedFrame struct {
+	message *websocket.PreparedMessage
+	size    int
+}
+
 type conn struct {
-	inner    *websocket.Conn
-	server   *server
-	acceptWg sync.WaitGroup
+	inner      *websocket.Conn
+	server     *server
+	acceptWg   sync.WaitGroup
+	acceptOnce sync.Once
+
+	capabilities   []string
+	handshakeMutex sync.RWMutex
+
+	reliable      *sockets.Reliable
+	reliableMutex sync.Mutex
 
 	encoder sockets.Encoder
 	pinger  *time.Ticker
@@ -35,9 +54,10 @@ type conn struct {
 	writer      writerCounter
 	writerMutex sync.Mutex
 
-	closeCb []func(err error)
-	errorCb func(err error)
-	fatalCb func(topic string, data interface{}, msg interface{})
+	closeCb   []func(err error)
+	errorCb   func(err error)
+	controlCb func(topic, value string)
+	fatalCb   func(topic string, data interface{}, msg interface{})
 
 	messageHandlers map[string]sockets.MessageHandler
 	replyHandlers   map[string]sockets.ReplyHandler
@@ -52,8 +72,9 @@ func newConn(inner *websocket.Conn, server *server) sockets.Conn {
 
 		pinger: time.NewTicker(pingTimeout),
 
-		closeCb: []func(err error){},
-		errorCb: func(err error) {},
+		closeCb:   []func(err error){},
+		errorCb:   func(err error) {},
+		controlCb: func(topic, value string) {},
 		// fatalCb must be nil to print default messages to terminal
 		messageHandlers: map[string]sockets.MessageHandler{},
 		replyHandlers:   map[string]sockets.ReplyHandler{},
@@ -76,7 +97,8 @@ func newConn(inner *websocket.Conn, server *server) sockets.Conn {
 }
 
 func (c *conn) Accept() {
-	c.acceptWg.Done()
+	// Connection may be already released by rejected handshake
+	c.acceptOnce.Do(c.acceptWg.Done)
 }
 
 func (c *conn) LocalAddr() net.Addr {
@@ -159,13 +181,18 @@ func (c *conn) readMessage() {
 
 	id, err := c.encoder.ReadString()
 	if err != nil {
-		c.errorCb(err)
+		c.errorCb(decodeError("", err))
 		return
 	}
 
 	topic, err := c.encoder.ReadString()
 	if err != nil {
-		c.errorCb(err)
+		c.errorCb(decodeError("", err))
+		return
+	}
+
+	if strings.HasPrefix(topic, sockets.ControlPrefix) {
+		c.handleControl(topic, id)
 		return
 	}
 
@@ -177,7 +204,12 @@ func (c *conn) readMessage() {
 
 	data := handler.Model()
 	if err := c.encoder.ReadData(data); err != nil {
-		c.errorCb(err)
+		c.errorCb(decodeError(topic, err))
+		return
+	}
+
+	if sockets.IsReliableID(id) && c.acknowledge(id) {
+		// Duplicated message is already handled
 		return
 	}
 
@@ -241,10 +273,52 @@ func (c *conn) WriteWithReply(topic string, data interface{}, handler sockets.Re
 	return nil
 }
 
+func (c *conn) WritePrepared(msg *sockets.PreparedMessage) error {
+	// Frames are shared between connections with the same encoder type
+	key := preparedFrameKey{encoder: reflect.TypeOf(c.encoder)}
+
+	value, err := msg.Frame(key, func() (interface{}, error) {
+		data, err := c.encode(msg.ID(), msg.Topic(), msg.Data())
+		if err != nil {
+			return nil, err
+		}
+
+		prepared, err := websocket.NewPreparedMessage(websocket.BinaryMessage, data)
+		if err != nil {
+			return nil, err
+		}
+
+		return &preparedFrame{message: prepared, size: len(data)}, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	frame := value.(*preparedFrame)
+
+	c.writerMutex.Lock()
+	defer c.writerMutex.Unlock()
+
+	if err := c.inner.WritePreparedMessage(frame.message); err != nil {
+		// Not a critical error (any critical errors we handle inside read loop)
+		return err
+	}
+
+	c.writer.Add(uint64(frame.size))
+	return nil
+}
+
 func (c *conn) write(id, topic string, data interface{}) error {
 	c.writerMutex.Lock()
 	defer c.writerMutex.Unlock()
 
+	// Message is encoded before writing, so encoding errors never break the frame
+	content, err := c.encodeLocked(id, topic, data)
+	if err != nil {
+		return err
+	}
+
 	writer, err := c.inner.NextWriter(websocket.BinaryMessage)
 	if err != nil {
 		return err
@@ -252,8 +326,8 @@ func (c *conn) write(id, topic string, data interface{}) error {
 
 	c.writer.Reset(writer)
 
-	if err := c.writeMessage(id, topic, data); err != nil {
-		// writeMessage can only return network errors that will be handled in readMessageLoop
+	if _, err := c.writer.Write(content); err != nil {
+		// Network errors will be handled in readMessageLoop
 		return err
 	}
 
@@ -265,8 +339,25 @@ func (c *conn) write(id, topic string, data interface{}) error {
 	return nil
 }
 
-func (c *conn) writeMessage(id, topic string, data interface{}) error {
-	c.encoder.ResetWriter(&c.writer)
+// encode encodes message to bytes using connection encoder
+func (c *conn) encode(id, topic string, data interface{}) ([]byte, error) {
+	c.writerMutex.Lock()
+	defer c.writerMutex.Unlock()
+
+	return c.encodeLocked(id, topic, data)
+}
+
+func (c *conn) encodeLocked(id, topic string, data interface{}) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	if err := c.writeMessage(buffer, id, topic, data); err != nil {
+		return nil, &sockets.EncodeError{Topic: topic, Err: err}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (c *conn) writeMessage(writer io.Writer, id, topic string, data interface{}) error {
+	c.encoder.ResetWriter(writer)
 
 	if err := c.encoder.WriteString(id); err != nil {
 		return err
@@ -288,6 +379,24 @@ func (c *conn) writePing() error {
 	return c.inner.WriteMessage(websocket.PingMessage, nil)
 }
 
+func (c *conn) WriteControl(topic, value string) error {
+	if !strings.HasPrefix(topic, sockets.ControlPrefix) {
+		return errors.Newf("websockets: \"%s\" is not a control topic", topic)
+	}
+
+	// Control messages have no data, empty raw message keeps frame format
+	return c.write(value, topic, sockets.RawMessage(nil))
+}
+
+func (c *conn) handleControl(topic, value string) {
+	if topic == sockets.TopicAck {
+		c.handleAck(value)
+		return
+	}
+
+	c.controlCb(topic, value)
+}
+
 func (c *conn) SetMessageHandlers(handlers ...sockets.MessageHandler) {
 	c.handlersMutex.Lock()
 	defer c.handlersMutex.Unlock()
@@ -337,6 +446,10 @@ func (c *conn) OnError(fn func(err error)) {
 	c.errorCb = fn
 }
 
+func (c *conn) OnControl(fn func(topic, value string)) {
+	c.controlCb = fn
+}
+
 func (c *conn) OnFatal(fn func(topic string, data interface{}, panicMsg interface{})) {
 	c.fatalCb = fn
 }
@@ -368,6 +481,17 @@ func Dial(addr string, headers http.Header) (sockets.Conn, error) {
 	return newConn(conn, nil), err
 }
 
+// decodeError ensures that any decoding error is returned as *sockets.DecodeError
+func decodeError(topic string, err error) error {
+	var decodeErr *sockets.DecodeError
+	if !errors.As(err, &decodeErr) {
+		return &sockets.DecodeError{Topic: topic, Err: err}
+	}
+
+	decodeErr.Topic = topic
+	return decodeErr
+}
+
 func isPointer(i interface{}) bool {
 	return reflect.TypeOf(i).Kind() == reflect.Ptr
 }
@@ -0,0 +1,139 @@
+package websockets
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/net/sockets"
+	"github.com/gorilla/websocket"
+)
+
+var (
+	closeTimeout = time.Second * 2
+)
+
+func (c *conn) Handshake(ctx context.Context, local sockets.Handshake) (sockets.Handshake, error) {
+	if local.Protocol == 0 {
+		local.Protocol = sockets.ProtocolVersion
+	}
+
+	local.Encoder = c.encoder.Name()
+
+	content, err := json.Marshal(local)
+	if err != nil {
+		return sockets.Handshake{}, err
+	}
+
+	// Handshake frames are text messages, so they never mix with binary ones
+	if err := c.writeText(content); err != nil {
+		return sockets.Handshake{}, err
+	}
+
+	remote, err := c.readHandshake(ctx)
+	if err != nil {
+		return sockets.Handshake{}, err
+	}
+
+	capabilities, err := sockets.Negotiate(local, remote)
+	if err != nil {
+		c.reject(err.(*sockets.HandshakeError))
+		return remote, err
+	}
+
+	c.handshakeMutex.Lock()
+	defer c.handshakeMutex.Unlock()
+
+	c.capabilities = capabilities
+	return remote, nil
+}
+
+func (c *conn) Capabilities() []string {
+	c.handshakeMutex.RLock()
+	defer c.handshakeMutex.RUnlock()
+
+	return append([]string{}, c.capabilities...)
+}
+
+func (c *conn) readHandshake(ctx context.Context) (sockets.Handshake, error) {
+	done := make(chan struct{})
+	cancelled := make(chan bool, 1)
+
+	go func() {
+		// Read can't be interrupted without breaking the connection,
+		// so cancelled handshake closes it (read deadline is left for ping loop)
+		select {
+		case <-ctx.Done():
+			_ = c.inner.Close()
+			cancelled <- true
+		case <-done:
+			cancelled <- false
+		}
+	}()
+
+	messageType, content, err := c.inner.ReadMessage()
+
+	close(done)
+	if <-cancelled {
+		// Read loop is released only after the read above is finished
+		c.Accept()
+		return sockets.Handshake{}, ctx.Err()
+	}
+
+	if err != nil {
+		// Read errors are permanent, read loop will handle the closure
+		c.release()
+
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) && closeErr.Code == websocket.CloseProtocolError {
+			// Other side rejected our handshake
+			return sockets.Handshake{}, &sockets.HandshakeError{Reason: closeErr.Text}
+		}
+
+		return sockets.Handshake{}, err
+	}
+
+	if messageType != websocket.TextMessage {
+		handshakeErr := &sockets.HandshakeError{Reason: "handshake frame expected"}
+
+		c.reject(handshakeErr)
+		return sockets.Handshake{}, handshakeErr
+	}
+
+	var remote sockets.Handshake
+	if err := json.Unmarshal(content, &remote); err != nil {
+		handshakeErr := &sockets.HandshakeError{Reason: "malformed handshake frame"}
+
+		c.reject(handshakeErr)
+		return sockets.Handshake{}, handshakeErr
+	}
+
+	return remote, nil
+}
+
+// reject closes the connection with handshake error as a reason
+func (c *conn) reject(err *sockets.HandshakeError) {
+	c.writerMutex.Lock()
+	_ = c.inner.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseProtocolError, err.Reason),
+		time.Now().Add(closeTimeout),
+	)
+	c.writerMutex.Unlock()
+
+	c.release()
+}
+
+// release closes the connection and releases read loop to handle the closure
+func (c *conn) release() {
+	_ = c.inner.Close()
+	c.Accept()
+}
+
+func (c *conn) writeText(content []byte) error {
+	c.writerMutex.Lock()
+	defer c.writerMutex.Unlock()
+
+	return c.inner.WriteMessage(websocket.TextMessage, content)
+}
@@ -21,6 +21,11 @@ func (w *writerCounter) Reset(writer io.Writer) {
 	w.writer = writer
 }
 
+// Add counts bytes written bypassing the writer
+func (w *writerCounter) Add(n uint64) {
+	w.count += n
+}
+
 func (w *writerCounter) Count() uint64 {
 	return w.count
 }
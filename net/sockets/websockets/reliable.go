@@ -0,0 +1,87 @@
+package websockets
+
+import (
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/net/sockets"
+)
+
+func (c *conn) WriteReliable(topic string, data interface{}) error {
+	state := c.getReliable()
+	if state == nil {
+		return sockets.ErrReliableDisabled
+	}
+
+	msg, err := state.Add(topic, data)
+	if err != nil {
+		return err
+	}
+
+	if err := c.write(msg.ID, msg.Topic, msg.Data); err != nil {
+		var encodeErr *sockets.EncodeError
+		if errors.As(err, &encodeErr) {
+			// Message will never be encoded, there is nothing to retransmit
+			state.Ack(msg.ID)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (c *conn) SetReliable(state *sockets.Reliable) {
+	c.reliableMutex.Lock()
+	c.reliable = state
+	c.reliableMutex.Unlock()
+
+	if state == nil {
+		// Reliable mode is disabled
+		return
+	}
+
+	for _, msg := range state.Pending() {
+		err := c.write(msg.ID, msg.Topic, msg.Data)
+		if err == nil {
+			continue
+		}
+
+		c.errorCb(err)
+
+		var encodeErr *sockets.EncodeError
+		if !errors.As(err, &encodeErr) {
+			// Connection is closed, messages are kept for the next one
+			return
+		}
+
+		// Message will never be encoded (e.g. encoder was changed)
+		state.Ack(msg.ID)
+	}
+}
+
+func (c *conn) getReliable() *sockets.Reliable {
+	c.reliableMutex.Lock()
+	defer c.reliableMutex.Unlock()
+
+	return c.reliable
+}
+
+// acknowledge acknowledges reliable message and reports whether it is a duplicate
+func (c *conn) acknowledge(id string) bool {
+	// Duplicates are acknowledged too, previous acknowledgement may be lost
+	if err := c.WriteControl(sockets.TopicAck, id); err != nil {
+		c.errorCb(err)
+	}
+
+	state := c.getReliable()
+	if state == nil {
+		return false
+	}
+
+	return state.Receive(id)
+}
+
+func (c *conn) handleAck(id string) {
+	if state := c.getReliable(); state != nil {
+		state.Ack(id)
+	}
+}
@@ -0,0 +1,228 @@
+package websockets_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/net/sockets"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// msgpackText is encodable only by msgpack encoder
+type msgpackText string
+
+func (t msgpackText) EncodeMsg(writer *msgp.Writer) error {
+	return writer.WriteString(string(t))
+}
+
+// waitPending waits until the state has the number of unacknowledged messages
+func waitPending(t *testing.T, state *sockets.Reliable, count int) []sockets.ReliableMessage {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for {
+		pending := state.Pending()
+		if len(pending) == count {
+			return pending
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d pending messages, got %d", count, len(pending))
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestReliableAck(t *testing.T) {
+	server := newTestServer(t)
+	client, conn := server.dial(t, nil)
+
+	received := newRecorder("topic")
+	client.SetMessageHandlers(received)
+
+	state := sockets.NewReliable(0)
+	conn.SetReliable(state)
+	conn.Accept()
+
+	for _, message := range []string{"first", "second"} {
+		if err := conn.WriteReliable("topic", text(message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Messages are numbered in order of writing
+	pending := waitPending(t, state, 2)
+	for n, msg := range pending {
+		if !sockets.IsReliableID(msg.ID) || !strings.HasSuffix(msg.ID, ":"+strconv.Itoa(n+1)) {
+			t.Fatalf("unexpected id %q of message %d", msg.ID, n+1)
+		}
+	}
+	if pending[0].ID == pending[1].ID {
+		t.Fatal("messages have the same id")
+	}
+
+	// Other side acknowledges messages after receiving
+	client.Accept()
+
+	// Messages are served concurrently, so the order is not checked
+	messages := map[string]bool{}
+	for n := 0; n < 2; n++ {
+		select {
+		case message := <-received.messages:
+			messages[message] = true
+		case <-time.After(testTimeout):
+			t.Fatal("message is not received")
+		}
+	}
+
+	if !messages["first"] || !messages["second"] {
+		t.Fatalf("unexpected messages %v", messages)
+	}
+
+	waitPending(t, state, 0)
+}
+
+func TestReliableRetransmit(t *testing.T) {
+	server := newTestServer(t)
+	_, conn := server.dial(t, nil)
+
+	state := sockets.NewReliable(0)
+	conn.SetReliable(state)
+	conn.Accept()
+
+	// Other side doesn't read messages, so they are not acknowledged
+	for _, message := range []string{"first", "second"} {
+		if err := conn.WriteReliable("topic", text(message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending := state.Pending()
+	if err := conn.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	client, reconnected := server.dialRaw(t)
+	reconnected.SetReliable(state)
+	reconnected.Accept()
+
+	for n, message := range []string{"first", "second"} {
+		id, _, data := readFrame(t, client)
+		if id != pending[n].ID || string(data) != string(text(message)) {
+			t.Fatalf("message %q is not retransmitted", message)
+		}
+	}
+
+	writeFrame(t, client, pending[0].ID, sockets.TopicAck, nil)
+
+	if remaining := waitPending(t, state, 1); remaining[0].ID != pending[1].ID {
+		t.Fatal("wrong message is acknowledged")
+	}
+}
+
+func TestReliableDuplicates(t *testing.T) {
+	server := newTestServer(t)
+	client, conn := server.dialRaw(t)
+
+	received := newRecorder("topic")
+	conn.SetMessageHandlers(received)
+	conn.SetReliable(sockets.NewReliable(0))
+	conn.Accept()
+
+	// Retransmitted message has the same id
+	for n := 0; n < 2; n++ {
+		writeFrame(t, client, "r:origin:1", "topic", text("message"))
+	}
+
+	received.expect(t, "message")
+	received.expectNothing(t)
+
+	// Duplicates are acknowledged too, previous acknowledgement may be lost
+	for n := 0; n < 2; n++ {
+		if id, topic, _ := readFrame(t, client); id != "r:origin:1" || topic != sockets.TopicAck {
+			t.Fatalf("unexpected %q message %q", topic, id)
+		}
+	}
+}
+
+func TestReliableOverflow(t *testing.T) {
+	server := newTestServer(t)
+	_, conn := server.dial(t, nil)
+
+	if err := conn.WriteReliable("topic", text("message")); !errors.Is(err, sockets.ErrReliableDisabled) {
+		t.Fatalf("expected disabled reliable mode, got %v", err)
+	}
+
+	state := sockets.NewReliable(2)
+	conn.SetReliable(state)
+
+	for n := 0; n < 2; n++ {
+		if err := conn.WriteReliable("topic", text("message")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := conn.WriteReliable("topic", text("message")); !errors.Is(err, sockets.ErrReliableOverflow) {
+		t.Fatalf("expected overflow, got %v", err)
+	}
+
+	if len(state.Pending()) != 2 {
+		t.Fatal("overflowed message is kept")
+	}
+}
+
+func TestReliableEncodeError(t *testing.T) {
+	server := newTestServer(t)
+	_, conn := server.dial(t, nil)
+
+	state := sockets.NewReliable(0)
+	conn.SetReliable(state)
+
+	var encodeErr *sockets.EncodeError
+	if err := conn.WriteReliable("topic", 42); !errors.As(err, &encodeErr) {
+		t.Fatalf("expected encode error, got %v", err)
+	}
+
+	if len(state.Pending()) != 0 {
+		t.Fatal("message failed to encode is kept")
+	}
+
+	if err := conn.WriteReliable("topic", msgpackText("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteReliable("topic", text("second")); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := state.Pending()
+	if err := conn.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// New connection can't encode the first message
+	_, reconnected := server.dial(t, nil)
+	reconnected.SetEncoder(sockets.NewProtobufEncoder())
+
+	errs := make(chan error, 4)
+	reconnected.OnError(func(err error) { errs <- err })
+	reconnected.SetReliable(state)
+
+	select {
+	case err := <-errs:
+		if !errors.As(err, &encodeErr) {
+			t.Fatalf("expected encode error, got %v", err)
+		}
+	default:
+		t.Fatal("encode error is not reported")
+	}
+
+	// The rest of messages are still retransmitted
+	if remaining := state.Pending(); len(remaining) != 1 || remaining[0].ID != pending[1].ID {
+		t.Fatal("message failed to encode is not dropped")
+	}
+}
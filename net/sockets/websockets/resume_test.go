@@ -0,0 +1,304 @@
+package websockets_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/net/sockets"
+	"github.com/foundation-framework/foundation/session"
+)
+
+// resumeClient is a client side of resumable session
+type resumeClient struct {
+	conn     sockets.Conn
+	received *recorder
+	tokens   chan string
+	closed   chan error
+
+	// detached is closed after the session handles closure of server side connection
+	detached chan struct{}
+}
+
+// connect dials the server and resumes session by the token (empty for a new session)
+func connect(t *testing.T, server *testServer, resumer *sockets.Resumer, token string) (*resumeClient, *sockets.Session, bool) {
+	t.Helper()
+
+	client, conn := server.dial(t, nil)
+
+	result := &resumeClient{
+		conn:     client,
+		received: newRecorder("topic"),
+		tokens:   make(chan string, 4),
+		closed:   make(chan error, 1),
+		detached: make(chan struct{}),
+	}
+
+	client.SetMessageHandlers(result.received)
+	client.OnControl(func(topic, value string) {
+		if topic == sockets.TopicSession {
+			result.tokens <- value
+		}
+	})
+	client.OnClose(func(err error) { result.closed <- err })
+	client.Accept()
+
+	sess, resumed := resumer.Session(conn, token)
+
+	// Session closure callback is set first, so the session is detached
+	// when this callback is called
+	conn.OnClose(func(error) { close(result.detached) })
+	conn.Accept()
+
+	return result, sess, resumed
+}
+
+// token waits for a resume token sent by the server
+func (c *resumeClient) token(t *testing.T) string {
+	t.Helper()
+
+	select {
+	case token := <-c.tokens:
+		return token
+	case <-time.After(testTimeout):
+		t.Fatal("resume token is not received")
+		return ""
+	}
+}
+
+// disconnect closes client connection and waits for the session to detach
+func (c *resumeClient) disconnect(t *testing.T) {
+	t.Helper()
+
+	if err := c.conn.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-c.detached:
+	case <-time.After(testTimeout):
+		t.Fatal("session is not detached")
+	}
+}
+
+// sessionEnd records closure of the session
+type sessionEnd struct {
+	errs chan error
+}
+
+func watchEnd(sess *sockets.Session) *sessionEnd {
+	result := &sessionEnd{errs: make(chan error, 4)}
+	sess.OnClose(func(err error) { result.errs <- err })
+
+	return result
+}
+
+// expect waits for the session end and checks that it ends only once
+func (e *sessionEnd) expect(t *testing.T, sess *sockets.Session) error {
+	t.Helper()
+
+	var err error
+	select {
+	case err = <-e.errs:
+	case <-time.After(testTimeout):
+		t.Fatal("session is not ended")
+	}
+
+	select {
+	case <-sess.Context().Done():
+	default:
+		t.Fatal("session context is not cancelled")
+	}
+
+	if len(sess.Rooms()) != 0 {
+		t.Fatal("ended session is still in rooms")
+	}
+
+	select {
+	case <-e.errs:
+		t.Fatal("session is ended twice")
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	return err
+}
+
+func TestResumeToken(t *testing.T) {
+	server := newTestServer(t)
+	resumer := sockets.NewResumer(session.NewHub(), testTimeout, 0)
+
+	first, sess, resumed := connect(t, server, resumer, "")
+	if resumed {
+		t.Fatal("new session is resumed")
+	}
+
+	token := first.token(t)
+	if token == "" || token != sess.Token() {
+		t.Fatalf("received token %q, %q expected", token, sess.Token())
+	}
+
+	sess.SetData("key", "value")
+	if err := sess.Join("room"); err != nil {
+		t.Fatal(err)
+	}
+
+	first.disconnect(t)
+
+	second, resumedSess, resumed := connect(t, server, resumer, token)
+	if !resumed || resumedSess != sess {
+		t.Fatal("session is not resumed")
+	}
+
+	reissued := second.token(t)
+	if reissued == token || reissued != sess.Token() {
+		t.Fatalf("token %q is not reissued", token)
+	}
+
+	if !sess.InRoom("room") || sess.GetData("key") != "value" {
+		t.Fatal("resumed session lost its state")
+	}
+
+	// Every token can be used only once
+	_, other, resumed := connect(t, server, resumer, token)
+	if resumed || other == sess {
+		t.Fatal("session is resumed by used token")
+	}
+}
+
+func TestResumeReplay(t *testing.T) {
+	hub := session.NewHub()
+	server := newTestServer(t)
+	resumer := sockets.NewResumer(hub, testTimeout, 3)
+
+	first, sess, _ := connect(t, server, resumer, "")
+	token := first.token(t)
+
+	if err := sess.Join("room"); err != nil {
+		t.Fatal(err)
+	}
+
+	first.disconnect(t)
+
+	// The oldest messages are dropped by the buffer limit
+	for _, message := range []string{"first", "second", "third", "fourth", "fifth"} {
+		if err := hub.To("room").Emit("topic", text(message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	second, conn := server.dialRaw(t)
+	if _, resumed := resumer.Session(conn, token); !resumed {
+		t.Fatal("session is not resumed")
+	}
+	conn.Accept()
+
+	// Buffer is not replayed twice
+	if err := hub.To("room").Emit("topic", text("sixth")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, message := range []string{"third", "fourth", "fifth", "sixth"} {
+		if received := readText(t, second); received != message {
+			t.Fatalf("received %q, %q expected", received, message)
+		}
+	}
+}
+
+func TestResumeExpiry(t *testing.T) {
+	hub := session.NewHub()
+	server := newTestServer(t)
+	resumer := sockets.NewResumer(hub, time.Millisecond*100, 0)
+
+	first, sess, _ := connect(t, server, resumer, "")
+	token := first.token(t)
+
+	if err := sess.Join("room"); err != nil {
+		t.Fatal(err)
+	}
+
+	end := watchEnd(sess)
+	first.disconnect(t)
+
+	if err := end.expect(t, sess); !errors.Is(err, sockets.ErrSessionExpired) {
+		t.Fatalf("expected expiry error, got %v", err)
+	}
+
+	if hub.Count("room") != 0 {
+		t.Fatal("expired session is still in the room")
+	}
+
+	_, other, resumed := connect(t, server, resumer, token)
+	if resumed || other == sess {
+		t.Fatal("expired session is resumed")
+	}
+}
+
+func TestResumeCloseDetached(t *testing.T) {
+	server := newTestServer(t)
+	resumer := sockets.NewResumer(session.NewHub(), testTimeout, 0)
+
+	first, sess, _ := connect(t, server, resumer, "")
+	token := first.token(t)
+
+	if err := sess.Join("room"); err != nil {
+		t.Fatal(err)
+	}
+
+	end := watchEnd(sess)
+	first.disconnect(t)
+
+	for n := 0; n < 2; n++ {
+		if err := sess.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := end.expect(t, sess); err != nil {
+		t.Fatalf("closed session ended with %v", err)
+	}
+
+	_, other, resumed := connect(t, server, resumer, token)
+	if resumed || other == sess {
+		t.Fatal("closed session is resumed")
+	}
+}
+
+func TestResumeOpenConn(t *testing.T) {
+	hub := session.NewHub()
+	server := newTestServer(t)
+	resumer := sockets.NewResumer(hub, testTimeout, 0)
+
+	first, sess, _ := connect(t, server, resumer, "")
+	token := first.token(t)
+
+	if err := sess.Join("room"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Other side reconnects before the closure is detected
+	second, _, resumed := connect(t, server, resumer, token)
+	if !resumed {
+		t.Fatal("session is not resumed")
+	}
+
+	select {
+	case <-first.closed:
+	case <-time.After(testTimeout):
+		t.Fatal("previous connection is not closed")
+	}
+
+	// Closure of the previous connection doesn't detach the session
+	if err := hub.To("room").Emit("topic", text("message")); err != nil {
+		t.Fatal(err)
+	}
+
+	second.received.expect(t, "message")
+	first.received.expectNothing(t)
+
+	select {
+	case <-sess.Context().Done():
+		t.Fatal("resumed session is ended")
+	default:
+	}
+}
@@ -0,0 +1,193 @@
+package websockets_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foundation-framework/foundation/net/sockets"
+	"github.com/foundation-framework/foundation/net/sockets/websockets"
+	"github.com/gorilla/websocket"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// testTimeout limits waiting for messages and callbacks
+const testTimeout = time.Second * 5
+
+// testServer is a websockets server listening on a local port
+type testServer struct {
+	url   string
+	conns chan sockets.Conn
+}
+
+func newTestServer(t *testing.T) *testServer {
+	result := &testServer{conns: make(chan sockets.Conn, 16)}
+
+	server := websockets.NewServer(nil)
+	server.OnConn(func(conn sockets.Conn, _ http.Header) {
+		result.conns <- conn
+	})
+
+	listener := httptest.NewServer(server.Handler())
+	t.Cleanup(listener.Close)
+
+	result.url = "ws" + strings.TrimPrefix(listener.URL, "http")
+	return result
+}
+
+// dial connects a client and returns both sides of the connection,
+// connections are not accepted yet
+func (s *testServer) dial(t *testing.T, header http.Header) (sockets.Conn, sockets.Conn) {
+	client, err := websockets.Dial(s.url, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	return client, s.accept(t)
+}
+
+// dialRaw connects a client reading frames in order of receiving
+// (sockets.Conn serves messages concurrently)
+func (s *testServer) dialRaw(t *testing.T) (*websocket.Conn, sockets.Conn) {
+	client, _, err := websocket.DefaultDialer.Dial(s.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client, s.accept(t)
+}
+
+// accept waits for server side of the connection
+func (s *testServer) accept(t *testing.T) sockets.Conn {
+	select {
+	case conn := <-s.conns:
+		t.Cleanup(func() { _ = conn.Close(context.Background()) })
+		return conn
+	case <-time.After(testTimeout):
+		t.Fatal("connection is not accepted")
+		return nil
+	}
+}
+
+// recorder is a message handler recording text messages of the topic
+type recorder struct {
+	topic    string
+	messages chan string
+}
+
+func newRecorder(topic string) *recorder {
+	return &recorder{topic: topic, messages: make(chan string, 64)}
+}
+
+func (r *recorder) Topic() string {
+	return r.topic
+}
+
+func (r *recorder) Model() interface{} {
+	return &sockets.RawMessage{}
+}
+
+func (r *recorder) Serve(data interface{}) interface{} {
+	text, _, err := msgp.ReadStringBytes(*data.(*sockets.RawMessage))
+	if err != nil {
+		text = err.Error()
+	}
+
+	r.messages <- text
+	return nil
+}
+
+// expect waits for messages in the order
+func (r *recorder) expect(t *testing.T, expected ...string) {
+	t.Helper()
+
+	for _, message := range expected {
+		select {
+		case received := <-r.messages:
+			if received != message {
+				t.Fatalf("received %q, %q expected", received, message)
+			}
+		case <-time.After(testTimeout):
+			t.Fatalf("%q is not received", message)
+		}
+	}
+}
+
+// expectNothing checks that no messages are received for a while
+func (r *recorder) expectNothing(t *testing.T) {
+	t.Helper()
+
+	select {
+	case received := <-r.messages:
+		t.Fatalf("unexpected message %q", received)
+	case <-time.After(time.Millisecond * 200):
+	}
+}
+
+// text returns raw message with encoded string
+func text(value string) sockets.RawMessage {
+	return msgp.AppendString(nil, value)
+}
+
+// readText reads the next message of raw client skipping control messages
+func readText(t *testing.T, client *websocket.Conn) string {
+	t.Helper()
+
+	for {
+		_, topic, data := readFrame(t, client)
+		if strings.HasPrefix(topic, sockets.ControlPrefix) {
+			continue
+		}
+
+		text, _, err := msgp.ReadStringBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return text
+	}
+}
+
+// readFrame reads the next frame of raw client
+func readFrame(t *testing.T, client *websocket.Conn) (string, string, []byte) {
+	t.Helper()
+
+	if err := client.SetReadDeadline(time.Now().Add(testTimeout)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, frame, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Message is an id, a topic and data
+	id, frame, err := msgp.ReadStringBytes(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, frame, err := msgp.ReadStringBytes(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id, topic, frame
+}
+
+// writeFrame writes a frame by raw client
+func writeFrame(t *testing.T, client *websocket.Conn, id, topic string, data []byte) {
+	t.Helper()
+
+	frame := msgp.AppendString(msgp.AppendString(nil, id), topic)
+	if err := client.WriteMessage(websocket.BinaryMessage, append(frame, data...)); err != nil {
+		t.Fatal(err)
+	}
+}
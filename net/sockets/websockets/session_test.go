@@ -0,0 +1,58 @@
+package websockets_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/net/sockets"
+	"github.com/foundation-framework/foundation/session"
+)
+
+func TestSessionBroadcastEncodeError(t *testing.T) {
+	server := newTestServer(t)
+	client, conn := server.dial(t, nil)
+
+	received := newRecorder("topic")
+	client.SetMessageHandlers(received)
+	client.Accept()
+
+	hub := session.NewHub()
+	sess := sockets.NewSession(hub, conn)
+	conn.Accept()
+
+	errs := make(chan error, 4)
+	sess.OnError(func(err error) { errs <- err })
+
+	if err := sess.Join("room"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Plain values are not encodable by the msgpack encoder
+	if err := hub.To("room").Emit("topic", 42); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errs:
+		var encodeErr *sockets.EncodeError
+		if !errors.As(err, &encodeErr) || encodeErr.Topic != "topic" {
+			t.Fatalf("expected encode error, got %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("encode error is not reported")
+	}
+
+	// Connection keeps working after the failure
+	if err := hub.To("room").Emit("topic", text("message")); err != nil {
+		t.Fatal(err)
+	}
+
+	received.expect(t, "message")
+
+	select {
+	case err := <-errs:
+		t.Fatalf("unexpected error %v", err)
+	default:
+	}
+}
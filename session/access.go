@@ -0,0 +1,201 @@
+package session
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/foundation-framework/foundation/errors"
+)
+
+// ErrAccessDenied is matched by errors returned when Authorizer denies access
+// (use errors.As with *AccessError to get the reason)
+var ErrAccessDenied = errors.New("session: access denied")
+
+// ErrNotMember is the reason of AccessError returned by ReplaySince
+// when the session is not a member of the room
+var ErrNotMember = errors.New("session: session is not a member of the room")
+
+// AccessAction describes an action checked by Authorizer
+type AccessAction string
+
+const (
+	AccessJoin      AccessAction = "join"
+	AccessBroadcast AccessAction = "broadcast"
+	AccessReplay    AccessAction = "replay"
+)
+
+// AccessError represents an action denied by Authorizer
+type AccessError struct {
+	Action AccessAction
+	Room   string
+	Topic  string
+
+	// Err is the reason returned by Authorizer
+	Err error
+}
+
+func (e *AccessError) Error() string {
+	return fmt.Sprintf("session: access to %s \"%s\" room denied: %v", e.Action, e.Room, e.Err)
+}
+
+func (e *AccessError) Unwrap() error {
+	return e.Err
+}
+
+func (e *AccessError) Is(target error) bool {
+	return target == ErrAccessDenied
+}
+
+// Authorizer checks access of sessions to rooms, returned error denies the action
+type Authorizer interface {
+	CanJoin(session Session, room string) error
+	CanBroadcast(session Session, room, topic string) error
+}
+
+// SetAuthorizer sets authorizer checking Join and broadcasts from sessions
+// (broadcasts without sender are not checked)
+func (p *Hub) SetAuthorizer(authorizer Authorizer) {
+	p.settingsMutex.Lock()
+	defer p.settingsMutex.Unlock()
+
+	p.authorizer = authorizer
+}
+
+func (p *Hub) getAuthorizer() Authorizer {
+	p.settingsMutex.RLock()
+	defer p.settingsMutex.RUnlock()
+
+	return p.authorizer
+}
+
+func (p *Hub) authorizeJoin(session Session, room string) error {
+	authorizer := p.getAuthorizer()
+	if authorizer == nil {
+		return nil
+	}
+
+	if err := authorizer.CanJoin(session, room); err != nil {
+		return &AccessError{Action: AccessJoin, Room: room, Err: err}
+	}
+
+	return nil
+}
+
+func (p *Hub) authorizeBroadcast(session Session, rooms []string, topic string) error {
+	authorizer := p.getAuthorizer()
+	if authorizer == nil || session == nil {
+		return nil
+	}
+
+	for _, room := range rooms {
+		if err := authorizer.CanBroadcast(session, room, topic); err != nil {
+			return &AccessError{Action: AccessBroadcast, Room: room, Topic: topic, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// Rules is Authorizer matching room names against patterns,
+// the first rule with matching pattern decides, rooms without rules are allowed
+//
+// Pattern "*" matches any sequence of characters including "/"
+// (e.g. "tenant-a/*" matches "tenant-a/x/y"), "?" matches any single character
+//
+// Add rule with "*" pattern last to deny rooms without rules
+type Rules struct {
+	join      []joinRule
+	broadcast []broadcastRule
+	mutex     sync.RWMutex
+}
+
+type joinRule struct {
+	pattern string
+	fn      func(session Session, room string) error
+}
+
+type broadcastRule struct {
+	pattern string
+	fn      func(session Session, room, topic string) error
+}
+
+func NewRules() *Rules {
+	return &Rules{}
+}
+
+// Join adds rule for joining rooms matching the pattern
+func (r *Rules) Join(pattern string, fn func(session Session, room string) error) *Rules {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.join = append(r.join, joinRule{pattern: pattern, fn: fn})
+	return r
+}
+
+// Broadcast adds rule for broadcasting to rooms matching the pattern
+func (r *Rules) Broadcast(pattern string, fn func(session Session, room, topic string) error) *Rules {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.broadcast = append(r.broadcast, broadcastRule{pattern: pattern, fn: fn})
+	return r
+}
+
+func (r *Rules) CanJoin(session Session, room string) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, rule := range r.join {
+		if matchPattern(rule.pattern, room) {
+			return rule.fn(session, room)
+		}
+	}
+
+	return nil
+}
+
+func (r *Rules) CanBroadcast(session Session, room, topic string) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, rule := range r.broadcast {
+		if matchPattern(rule.pattern, room) {
+			return rule.fn(session, room, topic)
+		}
+	}
+
+	return nil
+}
+
+// matchPattern reports whether the name matches the pattern,
+// unlike path.Match wildcards match across "/" separators
+func matchPattern(pattern, name string) bool {
+	p, n := []rune(pattern), []rune(name)
+
+	// Position of the last "*" in the pattern and the name position it matched up to
+	star, mark := -1, 0
+
+	i, j := 0, 0
+	for j < len(n) {
+		switch {
+		case i < len(p) && p[i] == '*':
+			star, mark = i, j
+			i++
+		case i < len(p) && (p[i] == '?' || p[i] == n[j]):
+			i++
+			j++
+		case star >= 0:
+			// Extending the last "*" by one more character
+			mark++
+			i, j = star+1, mark
+		default:
+			return false
+		}
+	}
+
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+
+	return i == len(p)
+}
@@ -0,0 +1,118 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/foundation-framework/foundation/errors"
+)
+
+var errTestDenied = errors.New("denied")
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{"*", "", true},
+		{"*", "room", true},
+		{"*", "tenant-a/x", true},
+		{"tenant-a/*", "tenant-a/x", true},
+		{"tenant-a/*", "tenant-a/x/y", true},
+		{"tenant-a/*", "tenant-b/x", false},
+		{"tenant-a/*", "tenant-a", false},
+		{"*/chat", "tenant-a/x/chat", true},
+		{"*/chat", "tenant-a/x/chat/log", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"room-?", "room-1", true},
+		{"room-?", "room-10", false},
+		{"room", "room", true},
+		{"room", "rooms", false},
+	}
+
+	for _, c := range cases {
+		if matchPattern(c.pattern, c.name) != c.matched {
+			t.Errorf("pattern \"%s\" matching \"%s\" must be %v", c.pattern, c.name, c.matched)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	allow := func(Session, string) error { return nil }
+	deny := func(Session, string) error { return errTestDenied }
+
+	rules := NewRules().
+		Join("tenant-a/*", deny).
+		Join("public/*", allow).
+		Join("*", deny).
+		Broadcast("public/announcements", func(Session, string, string) error { return errTestDenied })
+
+	session := &silentSession{id: "session"}
+
+	cases := []struct {
+		room    string
+		allowed bool
+	}{
+		{"tenant-a/x", false},
+		{"tenant-a/x/y", false},
+		{"public/x", true},
+		{"public/x/y", true},
+		{"other/x/y", false},
+		{"other", false},
+	}
+
+	for _, c := range cases {
+		if err := rules.CanJoin(session, c.room); (err == nil) != c.allowed {
+			t.Errorf("joining \"%s\" must be allowed: %v, got %v", c.room, c.allowed, err)
+		}
+	}
+
+	if err := rules.CanBroadcast(session, "public/announcements", "topic"); err == nil {
+		t.Error("broadcast to \"public/announcements\" must be denied")
+	}
+
+	// Rooms without rules are allowed
+	if err := rules.CanBroadcast(session, "public/x", "topic"); err != nil {
+		t.Errorf("broadcast to \"public/x\" must be allowed, got %v", err)
+	}
+}
+
+func TestAccessError(t *testing.T) {
+	hub := NewHub()
+	hub.SetAuthorizer(NewRules().
+		Join("private/*", func(Session, string) error { return errTestDenied }).
+		Broadcast("readonly", func(Session, string, string) error { return errTestDenied }))
+
+	session := &countingSession{silentSession: silentSession{id: "session"}}
+
+	joined, err := hub.Join(session, "private/x/y")
+	if joined || hub.InRoom(session, "private/x/y") {
+		t.Fatal("denied session joined the room")
+	}
+
+	var accessErr *AccessError
+	if !errors.Is(err, ErrAccessDenied) || !errors.Is(err, errTestDenied) || !errors.As(err, &accessErr) {
+		t.Fatalf("expected access error, got %v", err)
+	}
+	if accessErr.Action != AccessJoin || accessErr.Room != "private/x/y" {
+		t.Fatalf("unexpected access error %+v", accessErr)
+	}
+
+	if _, err := hub.Join(session, "readonly"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = hub.Broadcast(session, "readonly", "topic", nil)
+	if !errors.As(err, &accessErr) || accessErr.Action != AccessBroadcast || accessErr.Topic != "topic" {
+		t.Fatalf("expected broadcast access error, got %v", err)
+	}
+
+	// Broadcasts without sender are not checked
+	if err := hub.To("readonly").Emit("topic", nil); err != nil {
+		t.Fatal(err)
+	}
+	if session.served != 1 {
+		t.Fatalf("expected 1 message, got %d", session.served)
+	}
+}
@@ -1,10 +1,80 @@
 package session
 
+import "github.com/foundation-framework/foundation/errors"
+
+// ErrRegistryRequired is reported when message data is sent
+// through network adapters without registry (see Hub.SetRegistry)
+var ErrRegistryRequired = errors.New("session: registry is required to send data through adapter")
+
 // Adapter describes a mechanism to share rooms between servers
 type Adapter interface {
-	// Broadcast broadcasts message to other adapter members
-	Broadcast(sessionID, room, topic string, data interface{})
+	// Broadcast sends envelope to other adapter members
+	Broadcast(envelope *Envelope)
+
+	// HandleBroadcast handles envelopes received from other adapter members
+	HandleBroadcast(func(envelope *Envelope))
+}
+
+// RoomSubscriber is implemented by adapters able to receive
+// broadcasts only for rooms with local sessions
+//
+// Hub subscribes to the room when the first local session joins it
+// and unsubscribes when the last local session leaves it
+type RoomSubscriber interface {
+	SubscribeRoom(room string)
+	UnsubscribeRoom(room string)
+}
+
+// RoomFilter is implemented by RoomSubscriber adapters
+// which receive broadcasts to rooms only optionally
+//
+// RoomSubscriber adapters not implementing RoomFilter are considered filtering,
+// hub history can't be used with filtering adapters (see Hub.SetHistory)
+type RoomFilter interface {
+	// FiltersRooms reports whether broadcasts to rooms
+	// without local sessions are not received
+	FiltersRooms() bool
+}
+
+// SessionSubscriber is implemented by adapters able to receive
+// direct messages only for local sessions
+//
+// Hub subscribes to the session on Register and unsubscribes on Unregister
+type SessionSubscriber interface {
+	SubscribeSession(sessionID string)
+	UnsubscribeSession(sessionID string)
+}
+
+// Resyncer is implemented by adapters which may lose envelopes
+// (e.g. while reconnecting to the broker)
+//
+// Adapter calls fn when envelopes may have been lost, hub then announces
+// its sessions and asks other hubs to do the same
+type Resyncer interface {
+	HandleResync(fn func())
+}
+
+// localAdapter is implemented by adapters delivering envelopes
+// inside the process, so message data is passed without encoding
+type localAdapter interface {
+	local()
+}
+
+func isLocal(adapter Adapter) bool {
+	_, ok := adapter.(localAdapter)
+	return ok
+}
+
+// filtersRooms reports whether the adapter doesn't receive
+// broadcasts to rooms without local sessions
+func filtersRooms(adapter Adapter) bool {
+	if _, ok := adapter.(RoomSubscriber); !ok {
+		return false
+	}
+
+	if filter, ok := adapter.(RoomFilter); ok {
+		return filter.FiltersRooms()
+	}
 
-	// HandleBroadcast handles incoming broadcast
-	HandleBroadcast(func(sessionID, room, topic string, data interface{}))
+	return true
 }
@@ -0,0 +1,73 @@
+package session
+
+import "sync"
+
+// MemoryNetwork links memory adapters of several hubs inside a single process
+// (useful to simulate a cluster without external infrastructure)
+//
+// Envelopes are delivered synchronously to all other adapters of the network
+type MemoryNetwork struct {
+	adapters      []*memoryAdapter
+	adaptersMutex sync.RWMutex
+}
+
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{}
+}
+
+// NewAdapter creates adapter connected to the network
+func (n *MemoryNetwork) NewAdapter() Adapter {
+	adapter := &memoryAdapter{
+		network: n,
+		handler: func(*Envelope) {},
+	}
+
+	n.adaptersMutex.Lock()
+	defer n.adaptersMutex.Unlock()
+
+	n.adapters = append(n.adapters, adapter)
+	return adapter
+}
+
+func (n *MemoryNetwork) deliver(sender *memoryAdapter, envelope *Envelope) {
+	n.adaptersMutex.RLock()
+	adapters := n.adapters
+	n.adaptersMutex.RUnlock()
+
+	for _, adapter := range adapters {
+		if adapter == sender {
+			continue
+		}
+
+		// Every receiver gets its own copy, like it was sent over network
+		received := *envelope
+		adapter.getHandler()(&received)
+	}
+}
+
+type memoryAdapter struct {
+	network *MemoryNetwork
+
+	handler      func(*Envelope)
+	handlerMutex sync.RWMutex
+}
+
+func (a *memoryAdapter) Broadcast(envelope *Envelope) {
+	a.network.deliver(a, envelope)
+}
+
+func (a *memoryAdapter) local() {}
+
+func (a *memoryAdapter) HandleBroadcast(fn func(envelope *Envelope)) {
+	a.handlerMutex.Lock()
+	defer a.handlerMutex.Unlock()
+
+	a.handler = fn
+}
+
+func (a *memoryAdapter) getHandler() func(*Envelope) {
+	a.handlerMutex.RLock()
+	defer a.handlerMutex.RUnlock()
+
+	return a.handler
+}
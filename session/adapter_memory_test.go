@@ -0,0 +1,21 @@
+package session_test
+
+import (
+	"testing"
+
+	"github.com/foundation-framework/foundation/session"
+	"github.com/foundation-framework/foundation/session/adaptertest"
+)
+
+func TestMemoryAdapter(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T, n int) []session.Adapter {
+		network := session.NewMemoryNetwork()
+
+		adapters := make([]session.Adapter, n)
+		for i := range adapters {
+			adapters[i] = network.NewAdapter()
+		}
+
+		return adapters
+	})
+}
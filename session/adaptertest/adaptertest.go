@@ -0,0 +1,380 @@
+// Package adaptertest provides conformance tests for session.Adapter implementations
+//
+// Use Run inside a regular test of the adapter package:
+//
+//	func TestAdapter(t *testing.T) {
+//		adaptertest.Run(t, func(t *testing.T, n int) []session.Adapter {
+//			network := session.NewMemoryNetwork()
+//			...
+//		})
+//	}
+package adaptertest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foundation-framework/foundation/session"
+	"github.com/tinylib/msgp/msgp"
+)
+
+var (
+	// Timeout limits waiting for envelopes delivered through adapters
+	Timeout = time.Second * 5
+
+	// Settle is a time to wait for unexpected (e.g. duplicated) envelopes
+	Settle = time.Millisecond * 200
+)
+
+// Factory creates n adapters connected to each other
+type Factory func(t *testing.T, n int) []session.Adapter
+
+// Run runs conformance tests against adapters created by the factory
+func Run(t *testing.T, factory Factory) {
+	t.Run("Broadcast", func(t *testing.T) {
+		testBroadcast(t, factory)
+	})
+
+	t.Run("SenderExclusion", func(t *testing.T) {
+		testSenderExclusion(t, factory)
+	})
+
+	t.Run("Ordering", func(t *testing.T) {
+		testOrdering(t, factory)
+	})
+
+	t.Run("BroadcastOptions", func(t *testing.T) {
+		testBroadcastOptions(t, factory)
+	})
+
+	t.Run("Registry", func(t *testing.T) {
+		testRegistry(t, factory)
+	})
+}
+
+func testBroadcast(t *testing.T, factory Factory) {
+	hubs := newHubs(t, factory, 3)
+
+	sender := join(hubs[0], "sender", "room")
+	receivers := []*recorder{
+		join(hubs[1], "receiver-1", "room"),
+		join(hubs[2], "receiver-2", "room"),
+	}
+	outsider := join(hubs[1], "outsider", "other")
+
+	hubs[0].Broadcast(sender, "room", "message", nil)
+
+	for _, receiver := range receivers {
+		receiver.waitTopics(t, "message")
+	}
+
+	time.Sleep(Settle)
+
+	for _, receiver := range receivers {
+		receiver.expectTopics(t, "message")
+	}
+
+	outsider.expectTopics(t)
+}
+
+func testSenderExclusion(t *testing.T, factory Factory) {
+	hubs := newHubs(t, factory, 2)
+
+	sender := join(hubs[0], "sender", "room")
+	neighbour := join(hubs[0], "neighbour", "room")
+	remote := join(hubs[1], "remote", "room")
+
+	hubs[0].Broadcast(sender, "room", "message", nil)
+
+	neighbour.waitTopics(t, "message")
+	remote.waitTopics(t, "message")
+
+	time.Sleep(Settle)
+
+	// Echoed envelopes must not reach the sender or duplicate local delivery
+	sender.expectTopics(t)
+	neighbour.expectTopics(t, "message")
+	remote.expectTopics(t, "message")
+}
+
+func testOrdering(t *testing.T, factory Factory) {
+	hubs := newHubs(t, factory, 2)
+
+	sender := join(hubs[0], "sender", "room")
+	receiver := join(hubs[1], "receiver", "room")
+
+	topics := make([]string, 100)
+	for i := range topics {
+		topics[i] = fmt.Sprintf("message-%d", i)
+		hubs[0].Broadcast(sender, "room", topics[i], nil)
+	}
+
+	receiver.waitTopics(t, topics...)
+}
+
+func testBroadcastOptions(t *testing.T, factory Factory) {
+	hubs := newHubs(t, factory, 2)
+
+	sender := join(hubs[0], "sender", "a", "b")
+
+	// Every kind of session is on both hubs, options must work locally and remotely
+	both := make([]*recorder, len(hubs))
+	excluded := make([]*recorder, len(hubs))
+	muted := make([]*recorder, len(hubs))
+	outsiders := make([]*recorder, len(hubs))
+
+	for i, hub := range hubs {
+		both[i] = join(hub, fmt.Sprintf("both-%d", i), "a", "b")
+		excluded[i] = join(hub, fmt.Sprintf("excluded-%d", i), "a")
+		muted[i] = join(hub, fmt.Sprintf("muted-%d", i), "a", "muted")
+		outsiders[i] = join(hub, fmt.Sprintf("outsider-%d", i), "c")
+	}
+
+	// Several rooms without duplicates and with exclusions
+	err := hubs[0].To("a", "b").
+		From(sender).
+		Except("excluded-0", "excluded-1").
+		ExceptRooms("muted").
+		Emit("first", nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Broadcasts to different rooms may be routed differently by adapters,
+	// so every broadcast is awaited to keep the order
+	both[1].waitTopics(t, "first")
+
+	if err := hubs[0].To("a").From(sender).IncludeSender().Emit("second", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	both[1].waitTopics(t, "first", "second")
+
+	// Nil sender is the server
+	if err := hubs[0].To("b").From(nil).Emit("third", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	both[1].waitTopics(t, "first", "second", "third")
+
+	// Server broadcast from another hub
+	if err := hubs[1].To("a").Emit("fourth", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range hubs {
+		both[i].waitTopics(t, "first", "second", "third", "fourth")
+		excluded[i].waitTopics(t, "second", "fourth")
+		muted[i].waitTopics(t, "second", "fourth")
+	}
+
+	sender.waitTopics(t, "second", "third", "fourth")
+
+	time.Sleep(Settle)
+
+	for i := range hubs {
+		both[i].expectTopics(t, "first", "second", "third", "fourth")
+		excluded[i].expectTopics(t, "second", "fourth")
+		muted[i].expectTopics(t, "second", "fourth")
+		outsiders[i].expectTopics(t)
+	}
+
+	sender.expectTopics(t, "second", "third", "fourth")
+}
+
+func testRegistry(t *testing.T, factory Factory) {
+	hubs := newHubs(t, factory, 2)
+
+	for _, hub := range hubs {
+		registry := session.NewRegistry(session.NewMsgpackCodec())
+		registry.Register("model", &model{})
+
+		hub.SetRegistry(registry)
+	}
+
+	errs := make(chan error, 16)
+	hubs[1].OnError(func(err error) {
+		errs <- err
+	})
+
+	join(hubs[0], "sender", "room")
+	receiver := join(hubs[1], "receiver", "room")
+
+	broadcast := &model{Text: "broadcast", Count: 1}
+	if err := hubs[0].To("room").Emit("model", broadcast); err != nil {
+		t.Fatal(err)
+	}
+
+	receiver.waitTopics(t, "model")
+	receiver.expectData(t, 0, broadcast)
+
+	// Direct messages carry data too, receiver is known after its registration arrives
+	direct := &model{Text: "direct", Count: 2}
+
+	deadline := time.Now().Add(Timeout)
+	for !hubs[0].SendTo("receiver", "model", direct) {
+		if time.Now().After(deadline) {
+			t.Fatal("remote session is not known")
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	receiver.waitTopics(t, "model", "model")
+	receiver.expectData(t, 1, direct)
+
+	// Data of unregistered topic can't be decoded by the receiving hub
+	if err := hubs[0].To("room").Emit("unregistered", &model{}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("nil error reported")
+		}
+	case <-time.After(Timeout):
+		t.Fatal("decoding error of unregistered topic is not reported")
+	}
+
+	time.Sleep(Settle)
+	receiver.expectTopics(t, "model", "model")
+}
+
+func newHubs(t *testing.T, factory Factory, n int) []*session.Hub {
+	adapters := factory(t, n)
+	if len(adapters) != n {
+		t.Fatalf("factory returned %d adapters, %d expected", len(adapters), n)
+	}
+
+	hubs := make([]*session.Hub, n)
+	for i, adapter := range adapters {
+		hubs[i] = session.NewHub(adapter)
+		t.Cleanup(hubs[i].Close)
+	}
+
+	return hubs
+}
+
+func join(hub *session.Hub, id string, rooms ...string) *recorder {
+	result := &recorder{id: id}
+
+	hub.Register(result)
+	for _, room := range rooms {
+		hub.Join(result, room)
+	}
+
+	return result
+}
+
+// recorder is a session recording received topics and data
+type recorder struct {
+	id string
+
+	topics []string
+	data   []interface{}
+	mutex  sync.Mutex
+}
+
+func (r *recorder) ID() string {
+	return r.id
+}
+
+func (r *recorder) Close(context.Context) error {
+	return nil
+}
+
+func (r *recorder) ServeBroadcast(msg *session.Message) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.topics = append(r.topics, msg.Topic)
+	r.data = append(r.data, msg.Data)
+}
+
+func (r *recorder) received() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]string{}, r.topics...)
+}
+
+// waitTopics waits until the session receives expected number of topics
+func (r *recorder) waitTopics(t *testing.T, expected ...string) {
+	t.Helper()
+
+	deadline := time.Now().Add(Timeout)
+	for len(r.received()) < len(expected) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	r.expectTopics(t, expected...)
+}
+
+func (r *recorder) expectTopics(t *testing.T, expected ...string) {
+	t.Helper()
+
+	received := r.received()
+	if len(received) != len(expected) {
+		t.Fatalf("%s received %d messages %v, %d expected", r.id, len(received), received, len(expected))
+	}
+
+	for i := range expected {
+		if received[i] != expected[i] {
+			t.Fatalf("%s received %v, %v expected", r.id, received, expected)
+		}
+	}
+}
+
+// expectData checks data of the message received at the index
+func (r *recorder) expectData(t *testing.T, index int, expected *model) {
+	t.Helper()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	received, ok := r.data[index].(*model)
+	if !ok {
+		t.Fatalf("%s received %T, %T expected", r.id, r.data[index], expected)
+	}
+
+	if *received != *expected {
+		t.Fatalf("%s received %+v, %+v expected", r.id, received, expected)
+	}
+}
+
+// model is a message model encoded the same way as models generated by msgp
+type model struct {
+	Text  string
+	Count int
+}
+
+func (m *model) MarshalMsg(b []byte) ([]byte, error) {
+	b = msgp.AppendArrayHeader(b, 2)
+	b = msgp.AppendString(b, m.Text)
+	b = msgp.AppendInt(b, m.Count)
+
+	return b, nil
+}
+
+func (m *model) UnmarshalMsg(b []byte) ([]byte, error) {
+	size, b, err := msgp.ReadArrayHeaderBytes(b)
+	if err != nil {
+		return b, err
+	}
+
+	if size != 2 {
+		return b, msgp.ArrayError{Wanted: 2, Got: size}
+	}
+
+	if m.Text, b, err = msgp.ReadStringBytes(b); err != nil {
+		return b, err
+	}
+
+	m.Count, b, err = msgp.ReadIntBytes(b)
+	return b, err
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"time"
+
+	"github.com/foundation-framework/foundation/rand"
+)
+
+// Broadcaster builds a broadcast with targeting options
+//
+// All options are shared with other hubs through adapters
+type Broadcaster struct {
+	hub      *Hub
+	sender   Session
+	envelope Envelope
+}
+
+// To starts a broadcast to the rooms
+func (p *Hub) To(rooms ...string) *Broadcaster {
+	return &Broadcaster{
+		hub: p,
+		envelope: Envelope{
+			Type:  EnvelopeBroadcast,
+			Rooms: rooms,
+		},
+	}
+}
+
+// To adds rooms to the broadcast, sessions joined
+// several rooms receive the message only once
+func (b *Broadcaster) To(rooms ...string) *Broadcaster {
+	b.envelope.Rooms = append(b.envelope.Rooms, rooms...)
+	return b
+}
+
+// From sets sender of the broadcast, sender doesn't receive the message
+// unless IncludeSender is used (broadcast without sender or with nil sender
+// is sent by the server)
+func (b *Broadcaster) From(session Session) *Broadcaster {
+	b.sender = session
+	b.envelope.SessionID = ""
+
+	if session != nil {
+		b.envelope.SessionID = session.ID()
+	}
+
+	return b
+}
+
+// Except excludes sessions from the broadcast
+func (b *Broadcaster) Except(sessionIDs ...string) *Broadcaster {
+	b.envelope.ExceptSessions = append(b.envelope.ExceptSessions, sessionIDs...)
+	return b
+}
+
+// ExceptRooms excludes members of the rooms from the broadcast
+func (b *Broadcaster) ExceptRooms(rooms ...string) *Broadcaster {
+	b.envelope.ExceptRooms = append(b.envelope.ExceptRooms, rooms...)
+	return b
+}
+
+// IncludeSender makes sender receive the message too
+func (b *Broadcaster) IncludeSender() *Broadcaster {
+	b.envelope.IncludeSender = true
+	return b
+}
+
+// Emit sends the message to all targeted sessions,
+// *AccessError is returned when Authorizer denied the sender broadcasting to any room
+func (b *Broadcaster) Emit(topic string, data interface{}) error {
+	if err := b.hub.authorizeBroadcast(b.sender, b.envelope.Rooms, topic); err != nil {
+		return err
+	}
+
+	envelope := b.envelope
+	envelope.ID = rand.UUID()
+	envelope.Time = time.Now().UnixNano()
+	envelope.Topic = topic
+	envelope.Data = data
+
+	b.hub.record(&envelope)
+
+	// Firstly broadcast to adapters
+	b.hub.broadcastAdapters(&envelope)
+
+	// Then broadcast to current hub
+	b.hub.broadcast(&envelope)
+
+	return nil
+}
+
+// recipients returns local sessions targeted by the broadcast envelope
+func (p *Hub) recipients(envelope *Envelope) []Session {
+	excluded := map[string]struct{}{}
+	for _, id := range envelope.ExceptSessions {
+		excluded[id] = struct{}{}
+	}
+
+	if envelope.SessionID != "" && !envelope.IncludeSender {
+		excluded[envelope.SessionID] = struct{}{}
+	}
+
+	recipients := []Session{}
+	seen := map[Session]struct{}{}
+
+	// Membership is taken room by room, no lock is held while filtering
+	for _, room := range envelope.Rooms {
+		for _, session := range p.rooms.members(room) {
+			if _, ok := seen[session]; ok {
+				continue
+			}
+
+			seen[session] = struct{}{}
+
+			if _, ok := excluded[session.ID()]; ok {
+				continue
+			}
+
+			if p.inAnyRoom(session, envelope.ExceptRooms) {
+				continue
+			}
+
+			recipients = append(recipients, session)
+		}
+	}
+
+	return recipients
+}
+
+// inAnyRoom reports whether the session is a member of any room
+func (p *Hub) inAnyRoom(session Session, rooms []string) bool {
+	for _, room := range rooms {
+		if p.rooms.contains(session, room) {
+			return true
+		}
+	}
+
+	return false
+}
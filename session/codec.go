@@ -0,0 +1,7 @@
+package session
+
+// Codec describes encoding of message data crossing adapters
+type Codec interface {
+	Marshal(data interface{}) ([]byte, error)
+	Unmarshal(content []byte, model interface{}) error
+}
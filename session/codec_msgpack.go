@@ -0,0 +1,33 @@
+package session
+
+import (
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+type msgpackCodec struct {
+}
+
+// NewMsgpackCodec creates Codec for models generated by msgp
+func NewMsgpackCodec() Codec {
+	return &msgpackCodec{}
+}
+
+func (c *msgpackCodec) Marshal(data interface{}) ([]byte, error) {
+	marshaler, ok := data.(msgp.Marshaler)
+	if !ok {
+		return nil, errors.Newf("session: %T is not msgp.Marshaler", data)
+	}
+
+	return marshaler.MarshalMsg(nil)
+}
+
+func (c *msgpackCodec) Unmarshal(content []byte, model interface{}) error {
+	unmarshaler, ok := model.(msgp.Unmarshaler)
+	if !ok {
+		return errors.Newf("session: %T is not msgp.Unmarshaler", model)
+	}
+
+	_, err := unmarshaler.UnmarshalMsg(content)
+	return err
+}
@@ -0,0 +1,37 @@
+package session
+
+// SendTo sends message directly to the session and reports whether the session was found
+//
+// Session connected to another hub is reached through adapters
+func (p *Hub) SendTo(sessionID, topic string, data interface{}) bool {
+	if p.serveDirect(sessionID, topic, data) {
+		return true
+	}
+
+	if !p.isRemoteSession(sessionID) {
+		return false
+	}
+
+	p.broadcastAdapters(&Envelope{
+		Type:     EnvelopeDirect,
+		Receiver: sessionID,
+		Topic:    topic,
+		Data:     data,
+	})
+
+	return true
+}
+
+// serveDirect serves message to the local session and reports whether the session was found
+func (p *Hub) serveDirect(sessionID, topic string, data interface{}) bool {
+	p.sessionsMutex.RLock()
+	session := p.sessions[sessionID]
+	p.sessionsMutex.RUnlock()
+
+	if session == nil {
+		return false
+	}
+
+	session.ServeBroadcast(NewMessage(topic, data))
+	return true
+}
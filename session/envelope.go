@@ -0,0 +1,83 @@
+package session
+
+// EnvelopeType describes the purpose of the envelope
+type EnvelopeType int
+
+const (
+	// EnvelopeBroadcast carries a message broadcast to the rooms
+	EnvelopeBroadcast EnvelopeType = iota
+
+	// EnvelopeJoin reports that the session joined the room
+	EnvelopeJoin
+
+	// EnvelopeLeave reports that the session left the room
+	EnvelopeLeave
+
+	// EnvelopeSync requests other hubs (or a single hub by Receiver)
+	// to announce their presence
+	EnvelopeSync
+
+	// EnvelopeRegister reports that the session connected to the hub
+	EnvelopeRegister
+
+	// EnvelopeUnregister reports that the session disconnected from the hub
+	EnvelopeUnregister
+
+	// EnvelopeDirect carries a message sent directly to the session or the user
+	EnvelopeDirect
+
+	// EnvelopeBind reports that the session was bound to the user
+	EnvelopeBind
+
+	// EnvelopeUnbind reports that the session was unbound from the user
+	EnvelopeUnbind
+
+	// EnvelopeDisconnect requests other hubs to close all user sessions
+	EnvelopeDisconnect
+
+	// EnvelopeRoom carries the room metadata
+	EnvelopeRoom
+
+	// EnvelopeHeartbeat reports that the hub is alive
+	EnvelopeHeartbeat
+
+	// EnvelopeShutdown reports that the hub is closed and its sessions are gone
+	EnvelopeShutdown
+)
+
+// Envelope represents a message exchanged between hubs through adapters
+type Envelope struct {
+	Type EnvelopeType `json:"type"`
+
+	// Delivery metadata set by the sending hub: its node id,
+	// unique envelope id and sending time in unix nanoseconds
+	Node string `json:"node,omitempty"`
+	ID   string `json:"id,omitempty"`
+	Time int64  `json:"time,omitempty"`
+
+	// SessionID is a sender of broadcast or a subject of presence change
+	SessionID string `json:"session_id,omitempty"`
+
+	// Room is a room of presence change or metadata
+	Room     string    `json:"room,omitempty"`
+	RoomInfo *RoomInfo `json:"room_info,omitempty"`
+
+	// Broadcast targeting options (see Broadcaster)
+	Rooms          []string `json:"rooms,omitempty"`
+	ExceptSessions []string `json:"except_sessions,omitempty"`
+	ExceptRooms    []string `json:"except_rooms,omitempty"`
+	IncludeSender  bool     `json:"include_sender,omitempty"`
+
+	// Receiver is a receiver of direct message or a node id of sync request
+	Receiver string `json:"receiver,omitempty"`
+
+	// User is a receiver of direct message or a subject of user binding
+	User string `json:"user,omitempty"`
+
+	Topic string `json:"topic,omitempty"`
+
+	// Data is message data, when hub has Registry it is replaced
+	// by Payload encoded for the Topic before sending to adapters
+	Data    interface{} `json:"data,omitempty"`
+	Payload []byte      `json:"payload,omitempty"`
+}
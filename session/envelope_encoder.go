@@ -0,0 +1,7 @@
+package session
+
+// EnvelopeEncoder describes serialization of envelopes sent over network by adapters
+type EnvelopeEncoder interface {
+	Encode(envelope *Envelope) ([]byte, error)
+	Decode(content []byte) (*Envelope, error)
+}
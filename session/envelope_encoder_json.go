@@ -0,0 +1,26 @@
+package session
+
+import "encoding/json"
+
+type jsonEnvelopeEncoder struct {
+}
+
+// NewJSONEnvelopeEncoder creates EnvelopeEncoder based on JSON
+//
+// Envelope data is decoded into generic JSON values (e.g. map[string]interface{})
+func NewJSONEnvelopeEncoder() EnvelopeEncoder {
+	return &jsonEnvelopeEncoder{}
+}
+
+func (e *jsonEnvelopeEncoder) Encode(envelope *Envelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+func (e *jsonEnvelopeEncoder) Decode(content []byte) (*Envelope, error) {
+	envelope := &Envelope{}
+	if err := json.Unmarshal(content, envelope); err != nil {
+		return nil, err
+	}
+
+	return envelope, nil
+}
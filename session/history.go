@@ -0,0 +1,162 @@
+package session
+
+import (
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+)
+
+// ErrHistoryNotFound is returned by HistorySince when the message
+// is not stored in the room history (e.g. it was evicted)
+var ErrHistoryNotFound = errors.New("session: message not found in history")
+
+// ErrRoomsFiltered is returned by SetHistory when the hub uses adapters
+// receiving broadcasts only for rooms with local sessions
+var ErrRoomsFiltered = errors.New("session: adapter receives only rooms with local sessions")
+
+// HistoryEntry represents a message stored in the room history
+type HistoryEntry struct {
+	ID    string
+	Room  string
+	Topic string
+	Data  interface{}
+	Time  time.Time
+}
+
+// HistoryStore describes storage of room messages history
+//
+// Hub appends messages broadcast locally and received from adapters,
+// so every hub should have its own store
+type HistoryStore interface {
+	// Append stores the message in the room history
+	Append(entry *HistoryEntry) error
+
+	// Last returns up to limit last messages of the room in order they were appended
+	Last(room string, limit int) ([]*HistoryEntry, error)
+
+	// Since returns messages of the room appended after the message with id,
+	// ErrHistoryNotFound is returned when the message is not stored
+	Since(room, id string) ([]*HistoryEntry, error)
+}
+
+// SetHistory sets store used to keep history of broadcasts to rooms
+//
+// Hub records only broadcasts it receives, adapters receiving broadcasts
+// only for rooms with local sessions (see RoomFilter) would leave gaps
+// in the history, so ErrRoomsFiltered is returned when the hub uses such adapters
+// (e.g. redis adapter with PerRoom or NATS adapter without AllRooms)
+func (p *Hub) SetHistory(store HistoryStore) error {
+	for _, adapter := range p.adapters {
+		if store != nil && filtersRooms(adapter) {
+			return errors.Wrapf(ErrRoomsFiltered, "session: history can't be used with %T", adapter)
+		}
+	}
+
+	p.settingsMutex.Lock()
+	defer p.settingsMutex.Unlock()
+
+	p.history = store
+	return nil
+}
+
+func (p *Hub) getHistory() HistoryStore {
+	p.settingsMutex.RLock()
+	defer p.settingsMutex.RUnlock()
+
+	return p.history
+}
+
+// History returns up to limit last messages of the room,
+// nothing is returned when the hub has no history store
+func (p *Hub) History(room string, limit int) ([]*HistoryEntry, error) {
+	history := p.getHistory()
+	if history == nil {
+		return nil, nil
+	}
+
+	return history.Last(room, limit)
+}
+
+// HistorySince returns messages of the room broadcast after the message with id
+// (message ids are Message.ID received by sessions)
+func (p *Hub) HistorySince(room, id string) ([]*HistoryEntry, error) {
+	history := p.getHistory()
+	if history == nil {
+		return nil, ErrHistoryNotFound
+	}
+
+	return history.Since(room, id)
+}
+
+// JoinWithHistory joins the room and replays up to limit last messages of it
+// to the session, messages broadcast during the join may be received twice
+// (with the same Message.ID)
+func (p *Hub) JoinWithHistory(session Session, room string, limit int) (bool, error) {
+	joined, err := p.Join(session, room)
+	if err != nil || !joined {
+		return joined, err
+	}
+
+	entries, err := p.History(room, limit)
+	if err != nil {
+		return true, errors.Wrap(err, "session: failed to load history")
+	}
+
+	replay(session, entries)
+	return true, nil
+}
+
+// ReplaySince replays messages of the room broadcast after the message with id
+// to the session (e.g. to catch up after reconnect)
+//
+// Only room members can replay, *AccessError with ErrNotMember reason
+// is returned when the session is not in the room
+func (p *Hub) ReplaySince(session Session, room, id string) error {
+	if !p.InRoom(session, room) {
+		return &AccessError{Action: AccessReplay, Room: room, Err: ErrNotMember}
+	}
+
+	entries, err := p.HistorySince(room, id)
+	if err != nil {
+		return err
+	}
+
+	replay(session, entries)
+	return nil
+}
+
+// record appends broadcast envelope to the history of its rooms
+func (p *Hub) record(envelope *Envelope) {
+	history := p.getHistory()
+	if history == nil {
+		return
+	}
+
+	now := time.Now()
+	if envelope.Time != 0 {
+		now = time.Unix(0, envelope.Time)
+	}
+
+	for _, room := range envelope.Rooms {
+		err := history.Append(&HistoryEntry{
+			ID:    envelope.ID,
+			Room:  room,
+			Topic: envelope.Topic,
+			Data:  envelope.Data,
+			Time:  now,
+		})
+
+		if err != nil {
+			p.callErrorCb(errors.Wrap(err, "session: failed to append history"))
+		}
+	}
+}
+
+func replay(session Session, entries []*HistoryEntry) {
+	for _, entry := range entries {
+		msg := NewMessage(entry.Topic, entry.Data)
+		msg.ID = entry.ID
+
+		session.ServeBroadcast(msg)
+	}
+}
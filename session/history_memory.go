@@ -0,0 +1,104 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryHistory struct {
+	limit int
+	age   time.Duration
+
+	rooms      map[string][]*HistoryEntry
+	pruned     time.Time
+	roomsMutex sync.Mutex
+}
+
+// NewMemoryHistory creates HistoryStore keeping up to limit last messages
+// of every room not older than age, zero limit or age means no such bound
+//
+// Rooms are removed when all their messages are older than age,
+// so without age bound messages of every used room are kept
+func NewMemoryHistory(limit int, age time.Duration) HistoryStore {
+	return &memoryHistory{
+		limit:  limit,
+		age:    age,
+		rooms:  map[string][]*HistoryEntry{},
+		pruned: time.Now(),
+	}
+}
+
+func (h *memoryHistory) Append(entry *HistoryEntry) error {
+	h.roomsMutex.Lock()
+	defer h.roomsMutex.Unlock()
+
+	entries := append(h.rooms[entry.Room], entry)
+	if h.limit > 0 && len(entries) > h.limit {
+		entries = entries[len(entries)-h.limit:]
+	}
+
+	h.rooms[entry.Room] = entries
+
+	// Pruning all rooms once per age to forget abandoned ones
+	if h.age > 0 && time.Since(h.pruned) > h.age {
+		for room := range h.rooms {
+			h.prune(room)
+		}
+
+		h.pruned = time.Now()
+	}
+
+	return nil
+}
+
+func (h *memoryHistory) Last(room string, limit int) ([]*HistoryEntry, error) {
+	h.roomsMutex.Lock()
+	defer h.roomsMutex.Unlock()
+
+	entries := h.prune(room)
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	return append([]*HistoryEntry{}, entries...), nil
+}
+
+func (h *memoryHistory) Since(room, id string) ([]*HistoryEntry, error) {
+	h.roomsMutex.Lock()
+	defer h.roomsMutex.Unlock()
+
+	entries := h.prune(room)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ID == id {
+			return append([]*HistoryEntry{}, entries[i+1:]...), nil
+		}
+	}
+
+	return nil, ErrHistoryNotFound
+}
+
+// prune removes messages of the room older than age and returns the remaining ones
+// (roomsMutex must be locked)
+func (h *memoryHistory) prune(room string) []*HistoryEntry {
+	entries := h.rooms[room]
+	if h.age <= 0 {
+		return entries
+	}
+
+	deadline := time.Now().Add(-h.age)
+
+	expired := 0
+	for expired < len(entries) && entries[expired].Time.Before(deadline) {
+		expired++
+	}
+
+	if expired == len(entries) {
+		delete(h.rooms, room)
+		return nil
+	}
+
+	entries = entries[expired:]
+	h.rooms[room] = entries
+
+	return entries
+}
@@ -1,97 +1,548 @@
 package session
 
 import (
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/rand"
 )
 
 type Hub struct {
+	nodeID   string
+	seen     *idSet
 	adapters []Adapter
+	// Settings may be changed while adapters deliver envelopes
+	registry      *Registry
+	authorizer    Authorizer
+	history       HistoryStore
+	errorCb       func(err error)
+	settingsMutex sync.RWMutex
+
+	rooms *roomIndex
+
+	// Subscriptions of rooms through adapters (see RoomSubscriber)
+	subscriptions      map[string]*roomSubscription
+	subscriptionsMutex sync.Mutex
+
+	roomInfo      map[string]*RoomInfo
+	roomInfoMutex sync.RWMutex
+
+	// limitMutex serializes joins of rooms with members limit
+	limitMutex sync.Mutex
+
+	sessions      map[string]Session
+	sessionsMutex sync.RWMutex
+
+	users        map[string]map[Session]struct{}
+	sessionUsers map[Session]string
+	usersMutex   sync.RWMutex
+
+	// Sessions of other hubs known from adapters by node id
+	nodes       map[string]*remoteNode
+	remoteMutex sync.RWMutex
+
+	heartbeat      time.Duration
+	nodeTimeout    time.Duration
+	heartbeatMutex sync.RWMutex
+
+	done      chan struct{}
+	closeOnce sync.Once
 
-	rooms      map[string]map[Session]struct{}
-	roomsMutex sync.RWMutex
+	joinCb         []func(session Session, room string)
+	leaveCb        []func(session Session, room string)
+	roomEmptyCb    []func(room string)
+	presenceCb     []func(event PresenceEvent)
+	latencyCb      []func(node string, latency time.Duration)
+	callbacksMutex sync.RWMutex
 }
 
 func NewHub(adapters ...Adapter) *Hub {
 	result := &Hub{
+		nodeID:   rand.UUID(),
+		seen:     newIDSet(dedupWindow),
 		adapters: adapters,
-		rooms:    map[string]map[Session]struct{}{},
+		errorCb:  func(err error) {},
+		rooms:    newRoomIndex(),
+		roomInfo: map[string]*RoomInfo{},
+		sessions: map[string]Session{},
+		nodes:    map[string]*remoteNode{},
+
+		subscriptions: map[string]*roomSubscription{},
+
+		users:        map[string]map[Session]struct{}{},
+		sessionUsers: map[Session]string{},
+
+		heartbeat:   defaultHeartbeat,
+		nodeTimeout: defaultNodeTimeout,
+		done:        make(chan struct{}),
 	}
 
 	for _, adapter := range adapters {
-		adapter.HandleBroadcast(result.broadcast)
+		adapter := adapter
+
+		adapter.HandleBroadcast(func(envelope *Envelope) {
+			if result.accept(adapter, envelope) {
+				result.handleEnvelope(envelope)
+			}
+		})
+
+		if resyncer, ok := adapter.(Resyncer); ok {
+			resyncer.HandleResync(result.resync)
+		}
+	}
+
+	// Asking other hubs for their presence
+	result.broadcastAdapters(&Envelope{Type: EnvelopeSync})
+
+	if len(adapters) > 0 {
+		go result.heartbeatLoop()
 	}
 
 	return result
 }
 
-func (p *Hub) Broadcast(session Session, room, topic string, data interface{}) {
-	// Firstly broadcast to adapters
-	p.broadcastAdapters(session.ID(), room, topic, data)
+// SetRegistry sets registry used to encode message data sent to adapters
+// and to decode data received from them
+//
+// Registry is required to send message data through network adapters,
+// without it such messages are not sent and ErrRegistryRequired is passed to OnError
+//
+// All hubs sharing rooms must use registries with the same codec and models
+func (p *Hub) SetRegistry(registry *Registry) {
+	p.settingsMutex.Lock()
+	defer p.settingsMutex.Unlock()
 
-	// Then broadcast to current hub
-	p.broadcast(session.ID(), room, topic, data)
+	p.registry = registry
 }
 
-func (p *Hub) Join(session Session, room string) bool {
-	p.roomsMutex.Lock()
-	defer p.roomsMutex.Unlock()
+// OnError sets callback for non-critical errors (e.g. data encoding errors)
+//
+// Only one callback allowed, next calls will replace callback
+func (p *Hub) OnError(fn func(err error)) {
+	p.settingsMutex.Lock()
+	defer p.settingsMutex.Unlock()
 
-	if p.rooms[room] == nil {
-		p.rooms[room] = map[Session]struct{}{}
+	p.errorCb = fn
+}
+
+// OnSessionJoin sets callback for a session joining the room
+//
+// Multiple callback allowed
+func (p *Hub) OnSessionJoin(fn func(session Session, room string)) {
+	p.callbacksMutex.Lock()
+	defer p.callbacksMutex.Unlock()
+
+	p.joinCb = append(p.joinCb, fn)
+}
+
+// OnSessionLeave sets callback for a session leaving the room
+//
+// Multiple callback allowed
+func (p *Hub) OnSessionLeave(fn func(session Session, room string)) {
+	p.callbacksMutex.Lock()
+	defer p.callbacksMutex.Unlock()
+
+	p.leaveCb = append(p.leaveCb, fn)
+}
+
+// OnRoomEmpty sets callback for the last session leaving the room
+//
+// Multiple callback allowed
+func (p *Hub) OnRoomEmpty(fn func(room string)) {
+	p.callbacksMutex.Lock()
+	defer p.callbacksMutex.Unlock()
+
+	p.roomEmptyCb = append(p.roomEmptyCb, fn)
+}
+
+// Broadcast broadcasts message to the room members except the sender
+// (use To for more targeting options)
+func (p *Hub) Broadcast(session Session, room, topic string, data interface{}) error {
+	return p.To(room).From(session).Emit(topic, data)
+}
+
+// Rooms returns sorted names of all non-empty rooms
+// including rooms of sessions connected to other hubs
+func (p *Hub) Rooms() []string {
+	names := map[string]struct{}{}
+
+	for _, room := range p.rooms.names() {
+		names[room] = struct{}{}
 	}
 
-	if _, ok := p.rooms[room][session]; ok {
-		return false
+	for _, room := range p.remoteRooms() {
+		names[room] = struct{}{}
 	}
 
-	p.rooms[room][session] = struct{}{}
-	return true
+	rooms := make([]string, 0, len(names))
+	for room := range names {
+		rooms = append(rooms, room)
+	}
+
+	sort.Strings(rooms)
+	return rooms
 }
 
-func (p *Hub) Leave(session Session, room string) bool {
-	p.roomsMutex.Lock()
-	defer p.roomsMutex.Unlock()
+// Members returns snapshot of local sessions in the room
+// (use Presence to get sessions connected to other hubs)
+func (p *Hub) Members(room string) []Session {
+	return p.rooms.members(room)
+}
 
-	if p.rooms[room] == nil {
+// Count returns a number of sessions in the room
+// including sessions connected to other hubs
+func (p *Hub) Count(room string) int {
+	return p.rooms.count(room) + p.remoteCount(room)
+}
+
+// Exists reports whether the room has any sessions
+// including sessions connected to other hubs
+func (p *Hub) Exists(room string) bool {
+	return p.Count(room) > 0
+}
+
+// Register registers connected session in the hub
+func (p *Hub) Register(session Session) {
+	p.sessionsMutex.Lock()
+
+	p.sessions[session.ID()] = session
+	p.sessionsMutex.Unlock()
+
+	p.subscribeSession(session.ID())
+
+	p.broadcastAdapters(&Envelope{
+		Type:      EnvelopeRegister,
+		SessionID: session.ID(),
+	})
+}
+
+// Unregister removes closed session from the hub and all its rooms
+func (p *Hub) Unregister(session Session) {
+	p.sessionsMutex.Lock()
+	if p.sessions[session.ID()] == session {
+		delete(p.sessions, session.ID())
+	}
+	p.sessionsMutex.Unlock()
+
+	for _, room := range p.RoomsOf(session) {
+		p.Leave(session, room)
+	}
+
+	p.Unbind(session)
+	p.unsubscribeSession(session.ID())
+
+	p.broadcastAdapters(&Envelope{
+		Type:      EnvelopeUnregister,
+		SessionID: session.ID(),
+	})
+}
+
+// Join adds the session to the room and reports whether it was not a member,
+// ErrRoomFull is returned when the room reached its MaxMembers
+// and *AccessError when Authorizer denied joining
+func (p *Hub) Join(session Session, room string) (bool, error) {
+	if err := p.authorizeJoin(session, room); err != nil {
+		return false, err
+	}
+
+	joined, created, err := p.join(session, room)
+	if err != nil || !joined {
+		return false, err
+	}
+
+	if created {
+		p.syncRoomSubscription(room)
+		p.createRoomInfo(room)
+	}
+
+	p.callJoinCb(session, room)
+	p.changePresence(PresenceJoin, session.ID(), room)
+
+	return true, nil
+}
+
+// join reports whether session joined the room and whether the room is created
+func (p *Hub) join(session Session, room string) (bool, bool, error) {
+	limit := p.maxMembers(room)
+	if limit == 0 {
+		joined, created := p.rooms.join(session, room)
+		return joined, created, nil
+	}
+
+	p.limitMutex.Lock()
+	defer p.limitMutex.Unlock()
+
+	if p.rooms.contains(session, room) {
+		return false, false, nil
+	}
+
+	// Sessions of other hubs are counted too, but they may join concurrently
+	if p.Count(room) >= limit {
+		return false, false, ErrRoomFull
+	}
+
+	joined, created := p.rooms.join(session, room)
+	return joined, created, nil
+}
+
+func (p *Hub) Leave(session Session, room string) bool {
+	left, empty := p.rooms.leave(session, room)
+	if !left {
 		return false
 	}
 
-	// Removing session entry
-	delete(p.rooms[room], session)
+	p.callLeaveCb(session, room)
+	p.changePresence(PresenceLeave, session.ID(), room)
 
-	// Removing session room
-	if len(p.rooms[room]) == 0 {
-		delete(p.rooms, room)
+	if empty {
+		p.syncRoomSubscription(room)
+		p.clearRoomInfo(room)
+		p.callRoomEmptyCb(room)
 	}
 
 	return true
 }
 
-func (p *Hub) iterateRoom(room string, fn func(session Session)) {
-	p.roomsMutex.RLock()
-	defer p.roomsMutex.RUnlock()
+// RoomsOf returns sorted snapshot of rooms joined by the session
+func (p *Hub) RoomsOf(session Session) []string {
+	rooms := p.rooms.roomsOf(session)
 
-	if p.rooms[room] == nil {
+	sort.Strings(rooms)
+	return rooms
+}
+
+// InRoom reports whether the session is a member of the room
+func (p *Hub) InRoom(session Session, room string) bool {
+	return p.rooms.contains(session, room)
+}
+
+func (p *Hub) broadcast(envelope *Envelope) {
+	// Message is shared to allow receivers to encode data only once
+	msg := NewMessage(envelope.Topic, envelope.Data)
+	if envelope.ID != "" {
+		msg.ID = envelope.ID
+	}
+
+	// Serving outside the lock, receivers may be slow
+	for _, session := range p.recipients(envelope) {
+		session.ServeBroadcast(msg)
+	}
+}
+
+func (p *Hub) broadcastAdapters(envelope *Envelope) {
+	if len(p.adapters) == 0 {
 		return
 	}
 
-	for session := range p.rooms[room] {
-		fn(session)
+	// Envelope is copied, the original one is used for local delivery
+	envelope = p.stamp(envelope)
+
+	if registry := p.getRegistry(); registry != nil && envelope.Data != nil {
+		payload, err := registry.Encode(envelope.Topic, envelope.Data)
+		if err != nil {
+			p.callErrorCb(err)
+			return
+		}
+
+		envelope.Data = nil
+		envelope.Payload = payload
 	}
 
-	return
+	for _, adapter := range p.adapters {
+		if envelope.Data != nil && !isLocal(adapter) {
+			// Raw data can't be restored by remote hubs without registry
+			p.callErrorCb(errors.Wrapf(ErrRegistryRequired, "session: failed to send \"%s\" data", envelope.Topic))
+			continue
+		}
+
+		adapter.Broadcast(envelope)
+	}
 }
 
-func (p *Hub) broadcast(sessionID, room, topic string, data interface{}) {
-	p.iterateRoom(room, func(session Session) {
-		if session.ID() != sessionID {
-			session.ServeBroadcast(topic, data)
+// roomSubscription serializes subscription changes of a single room
+type roomSubscription struct {
+	subscribed bool
+	mutex      sync.Mutex
+
+	// refs is a number of goroutines changing the subscription
+	// (guarded by Hub.subscriptionsMutex)
+	refs int
+}
+
+// syncRoomSubscription subscribes adapters to the room with local sessions
+// and unsubscribes from the room without them
+//
+// Subscription follows the current membership, so concurrent creation
+// and removal of the room can't leave the room without subscription,
+// only changes of the same room wait for each other
+func (p *Hub) syncRoomSubscription(room string) {
+	if len(p.adapters) == 0 {
+		return
+	}
+
+	subscription := p.acquireSubscription(room)
+	defer p.releaseSubscription(room, subscription)
+
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	wanted := p.rooms.count(room) > 0
+	if subscription.subscribed == wanted {
+		return
+	}
+
+	subscription.subscribed = wanted
+
+	for _, adapter := range p.adapters {
+		subscriber, ok := adapter.(RoomSubscriber)
+		if !ok {
+			continue
 		}
-	})
+
+		if wanted {
+			subscriber.SubscribeRoom(room)
+		} else {
+			subscriber.UnsubscribeRoom(room)
+		}
+	}
+}
+
+func (p *Hub) acquireSubscription(room string) *roomSubscription {
+	p.subscriptionsMutex.Lock()
+	defer p.subscriptionsMutex.Unlock()
+
+	subscription := p.subscriptions[room]
+	if subscription == nil {
+		subscription = &roomSubscription{}
+		p.subscriptions[room] = subscription
+	}
+
+	subscription.refs++
+	return subscription
+}
+
+// releaseSubscription forgets unsubscribed room nobody is changing
+func (p *Hub) releaseSubscription(room string, subscription *roomSubscription) {
+	p.subscriptionsMutex.Lock()
+	defer p.subscriptionsMutex.Unlock()
+
+	subscription.refs--
+	if subscription.refs == 0 && !subscription.subscribed {
+		delete(p.subscriptions, room)
+	}
+}
+
+func (p *Hub) subscribeSession(sessionID string) {
+	for _, adapter := range p.adapters {
+		if subscriber, ok := adapter.(SessionSubscriber); ok {
+			subscriber.SubscribeSession(sessionID)
+		}
+	}
 }
 
-func (p *Hub) broadcastAdapters(sessionID, room, topic string, data interface{}) {
+func (p *Hub) unsubscribeSession(sessionID string) {
 	for _, adapter := range p.adapters {
-		adapter.Broadcast(sessionID, room, topic, data)
+		if subscriber, ok := adapter.(SessionSubscriber); ok {
+			subscriber.UnsubscribeSession(sessionID)
+		}
+	}
+}
+
+func (p *Hub) handleEnvelope(envelope *Envelope) {
+	p.touchNode(envelope)
+
+	if registry := p.getRegistry(); registry != nil && envelope.Payload != nil {
+		data, err := registry.Decode(envelope.Topic, envelope.Payload)
+		if err != nil {
+			p.callErrorCb(err)
+			return
+		}
+
+		envelope.Data = data
+	}
+
+	switch envelope.Type {
+	case EnvelopeHeartbeat:
+		// Node is already touched
+	case EnvelopeShutdown:
+		p.purgeNode(envelope.Node)
+	case EnvelopeBroadcast:
+		p.record(envelope)
+		p.broadcast(envelope)
+	case EnvelopeJoin:
+		p.changeRemotePresence(envelope.Node, PresenceJoin, envelope.SessionID, envelope.Room)
+	case EnvelopeLeave:
+		p.changeRemotePresence(envelope.Node, PresenceLeave, envelope.SessionID, envelope.Room)
+	case EnvelopeSync:
+		// Sync may be requested from a single hub
+		if envelope.Receiver == "" || envelope.Receiver == p.nodeID {
+			p.announce()
+		}
+	case EnvelopeRegister:
+		p.changeRemoteSession(envelope.Node, envelope.SessionID, true)
+	case EnvelopeUnregister:
+		p.changeRemoteSession(envelope.Node, envelope.SessionID, false)
+	case EnvelopeDirect:
+		if envelope.Receiver != "" {
+			p.serveDirect(envelope.Receiver, envelope.Topic, envelope.Data)
+		}
+		if envelope.User != "" {
+			p.serveUser(envelope.User, envelope.Topic, envelope.Data)
+		}
+	case EnvelopeBind:
+		p.changeRemoteUser(envelope.Node, envelope.SessionID, envelope.User, true)
+	case EnvelopeUnbind:
+		p.changeRemoteUser(envelope.Node, envelope.SessionID, envelope.User, false)
+	case EnvelopeDisconnect:
+		p.disconnectUser(envelope.User)
+	case EnvelopeRoom:
+		p.changeRemoteRoomInfo(envelope.Room, envelope.RoomInfo)
+	}
+}
+
+func (p *Hub) getRegistry() *Registry {
+	p.settingsMutex.RLock()
+	defer p.settingsMutex.RUnlock()
+
+	return p.registry
+}
+
+func (p *Hub) callErrorCb(err error) {
+	p.settingsMutex.RLock()
+	fn := p.errorCb
+	p.settingsMutex.RUnlock()
+
+	fn(err)
+}
+
+func (p *Hub) callJoinCb(session Session, room string) {
+	p.callbacksMutex.RLock()
+	callbacks := p.joinCb
+	p.callbacksMutex.RUnlock()
+
+	for _, fn := range callbacks {
+		fn(session, room)
+	}
+}
+
+func (p *Hub) callLeaveCb(session Session, room string) {
+	p.callbacksMutex.RLock()
+	callbacks := p.leaveCb
+	p.callbacksMutex.RUnlock()
+
+	for _, fn := range callbacks {
+		fn(session, room)
+	}
+}
+
+func (p *Hub) callRoomEmptyCb(room string) {
+	p.callbacksMutex.RLock()
+	callbacks := p.roomEmptyCb
+	p.callbacksMutex.RUnlock()
+
+	for _, fn := range callbacks {
+		fn(room)
 	}
 }
@@ -0,0 +1,317 @@
+package session
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+)
+
+type subscriberAdapter struct {
+	rooms map[string]bool
+	mutex sync.Mutex
+
+	// subscribeCb and unsubscribeCb are called before subscription changes
+	subscribeCb   func(room string)
+	unsubscribeCb func(room string)
+}
+
+func (a *subscriberAdapter) Broadcast(*Envelope)             {}
+func (a *subscriberAdapter) HandleBroadcast(func(*Envelope)) {}
+
+func (a *subscriberAdapter) SubscribeRoom(room string) {
+	if a.subscribeCb != nil {
+		a.subscribeCb(room)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.rooms[room] = true
+}
+
+func (a *subscriberAdapter) UnsubscribeRoom(room string) {
+	if a.unsubscribeCb != nil {
+		a.unsubscribeCb(room)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	delete(a.rooms, room)
+}
+
+func (a *subscriberAdapter) subscribed(room string) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.rooms[room]
+}
+
+func TestRoomSubscriptionFollowsMembership(t *testing.T) {
+	adapter := &subscriberAdapter{rooms: map[string]bool{}}
+
+	hub := NewHub(adapter)
+	defer hub.Close()
+
+	leaving := &silentSession{id: "leaving"}
+	joining := &silentSession{id: "joining"}
+
+	// Room is created again while the last session leaving it is unsubscribing
+	adapter.unsubscribeCb = func(room string) {
+		adapter.unsubscribeCb = nil
+
+		joined := make(chan struct{})
+		go func() {
+			_, _ = hub.Join(joining, room)
+			close(joined)
+		}()
+
+		select {
+		case <-joined:
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
+
+	if _, err := hub.Join(leaving, "room"); err != nil {
+		t.Fatal(err)
+	}
+
+	hub.Leave(leaving, "room")
+
+	// Waiting for the concurrent join
+	deadline := time.Now().Add(time.Second)
+	for !hub.InRoom(joining, "room") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	time.Sleep(time.Millisecond * 10)
+
+	if !adapter.subscribed("room") {
+		t.Fatal("room with local sessions is not subscribed")
+	}
+
+	hub.Leave(joining, "room")
+
+	if adapter.subscribed("room") {
+		t.Fatal("empty room is subscribed")
+	}
+}
+
+func TestRoomSubscriptionsAreIndependent(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	adapter := &subscriberAdapter{rooms: map[string]bool{}}
+	adapter.subscribeCb = func(room string) {
+		if room == "slow" {
+			close(entered)
+			<-release
+		}
+	}
+
+	hub := NewHub(adapter)
+	defer hub.Close()
+
+	go func() {
+		_, _ = hub.Join(&silentSession{id: "slow"}, "slow")
+	}()
+
+	<-entered
+	defer close(release)
+
+	joined := make(chan struct{})
+	go func() {
+		_, _ = hub.Join(&silentSession{id: "fast"}, "fast")
+		close(joined)
+	}()
+
+	select {
+	case <-joined:
+	case <-time.After(time.Second):
+		t.Fatal("room subscription waits for subscription of another room")
+	}
+
+	if !adapter.subscribed("fast") {
+		t.Fatal("room with local sessions is not subscribed")
+	}
+}
+
+func TestDataWithoutRegistry(t *testing.T) {
+	hub := NewHub(&subscriberAdapter{rooms: map[string]bool{}})
+	defer hub.Close()
+
+	var reported error
+	hub.OnError(func(err error) {
+		reported = err
+	})
+
+	sender := &silentSession{id: "sender"}
+	if _, err := hub.Join(sender, "room"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hub.Broadcast(sender, "room", "topic", "data"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(reported, ErrRegistryRequired) {
+		t.Fatalf("expected ErrRegistryRequired, got %v", reported)
+	}
+}
+
+func TestCreatedRoomKeepsMetadata(t *testing.T) {
+	network := NewMemoryNetwork()
+
+	owner := NewHub(network.NewAdapter())
+	defer owner.Close()
+
+	if _, err := owner.Join(&silentSession{id: "owner"}, "room"); err != nil {
+		t.Fatal(err)
+	}
+
+	owner.UpdateRoom("room", func(info *RoomInfo) { info.Title = "title" })
+
+	// Room created on another hub must not wipe shared metadata
+	other := NewHub(network.NewAdapter())
+	defer other.Close()
+
+	// Metadata is not received yet
+	other.roomInfoMutex.Lock()
+	delete(other.roomInfo, "room")
+	other.roomInfoMutex.Unlock()
+
+	if _, err := other.Join(&silentSession{id: "other"}, "room"); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, _ := owner.Room("room"); info.Title != "title" {
+		t.Fatalf("expected \"title\", got %q", info.Title)
+	}
+
+	// Other hub learns metadata on the next update
+	owner.SetRoomData("room", "key", "value")
+
+	for _, hub := range []*Hub{owner, other} {
+		if info, _ := hub.Room("room"); info.Title != "title" {
+			t.Fatalf("expected \"title\", got %q", info.Title)
+		}
+	}
+}
+
+type countingSession struct {
+	silentSession
+	served int
+}
+
+func (s *countingSession) ServeBroadcast(*Message) { s.served++ }
+
+func TestReplaySinceRequiresMembership(t *testing.T) {
+	hub := NewHub()
+	if err := hub.SetHistory(NewMemoryHistory(10, time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	member := &countingSession{silentSession: silentSession{id: "member"}}
+	if _, err := hub.Join(member, "room"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := hub.To("room").Emit("topic", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := hub.History("room", 2)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 history entries, got %d (%v)", len(entries), err)
+	}
+
+	stranger := &countingSession{silentSession: silentSession{id: "stranger"}}
+
+	err = hub.ReplaySince(stranger, "room", entries[0].ID)
+	if !errors.Is(err, ErrAccessDenied) || !errors.Is(err, ErrNotMember) {
+		t.Fatalf("expected ErrNotMember access error, got %v", err)
+	}
+	if stranger.served != 0 {
+		t.Fatal("history is replayed to non-member")
+	}
+
+	member.served = 0
+	if err := hub.ReplaySince(member, "room", entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if member.served != 1 {
+		t.Fatalf("expected 1 replayed message, got %d", member.served)
+	}
+}
+
+func TestHistoryRejectsRoomSubscribers(t *testing.T) {
+	hub := NewHub(&subscriberAdapter{rooms: map[string]bool{}})
+	defer hub.Close()
+
+	if err := hub.SetHistory(NewMemoryHistory(10, time.Minute)); !errors.Is(err, ErrRoomsFiltered) {
+		t.Fatalf("expected ErrRoomsFiltered, got %v", err)
+	}
+}
+
+type resyncAdapter struct {
+	resync func()
+
+	envelopes []*Envelope
+	mutex     sync.Mutex
+}
+
+func (a *resyncAdapter) Broadcast(envelope *Envelope) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.envelopes = append(a.envelopes, envelope)
+}
+
+func (a *resyncAdapter) HandleBroadcast(func(*Envelope)) {}
+func (a *resyncAdapter) HandleResync(fn func())          { a.resync = fn }
+
+func (a *resyncAdapter) sent() map[EnvelopeType]int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	types := map[EnvelopeType]int{}
+	for _, envelope := range a.envelopes {
+		types[envelope.Type]++
+	}
+
+	a.envelopes = nil
+	return types
+}
+
+func TestResync(t *testing.T) {
+	adapter := &resyncAdapter{}
+
+	hub := NewHub(adapter)
+	defer hub.Close()
+
+	session := &silentSession{id: "session"}
+	hub.Register(session)
+
+	if _, err := hub.Join(session, "room"); err != nil {
+		t.Fatal(err)
+	}
+
+	adapter.sent()
+	adapter.resync()
+
+	sent := adapter.sent()
+	if sent[EnvelopeRegister] != 1 || sent[EnvelopeJoin] != 1 || sent[EnvelopeSync] != 1 {
+		t.Fatalf("local state is not announced on resync: %v", sent)
+	}
+
+	hub.remoteMutex.RLock()
+	defer hub.remoteMutex.RUnlock()
+
+	if len(hub.nodes) != 0 {
+		t.Fatal("resync created remote node")
+	}
+}
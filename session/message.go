@@ -0,0 +1,47 @@
+package session
+
+import (
+	"sync"
+
+	"github.com/foundation-framework/foundation/rand"
+)
+
+// Message represents broadcast message shared between all receivers
+//
+// Receivers may use Shared to store data derived from the message
+// (e.g. encoded bytes) once per broadcast instead of once per receiver
+type Message struct {
+	// ID is unique message id, broadcast messages have
+	// the same id on all hubs and in the room history
+	ID string
+
+	Topic string
+	Data  interface{}
+
+	shared      map[interface{}]interface{}
+	sharedMutex sync.Mutex
+}
+
+func NewMessage(topic string, data interface{}) *Message {
+	return &Message{
+		ID:     rand.UUID(),
+		Topic:  topic,
+		Data:   data,
+		shared: map[interface{}]interface{}{},
+	}
+}
+
+// Shared returns value stored by the key, fn is used to create missing value
+func (m *Message) Shared(key interface{}, fn func() interface{}) interface{} {
+	m.sharedMutex.Lock()
+	defer m.sharedMutex.Unlock()
+
+	if value, ok := m.shared[key]; ok {
+		return value
+	}
+
+	value := fn()
+	m.shared[key] = value
+
+	return value
+}
@@ -0,0 +1,316 @@
+package nats
+
+import (
+	"encoding/base64"
+	"sync"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/session"
+	"github.com/nats-io/nats.go"
+)
+
+// Options describes NATS adapter options
+type Options struct {
+	// Prefix is a prefix of NATS subjects ("session" used if empty)
+	Prefix string
+
+	// AllRooms makes adapter subscribe to subjects of all rooms,
+	// so the node receives broadcasts to rooms without local sessions
+	// (required to use hub history, see session.Hub.SetHistory)
+	AllRooms bool
+
+	// Encoder is used to serialize envelopes (JSON used if nil)
+	Encoder session.EnvelopeEncoder
+}
+
+// Adapter is session.Adapter based on NATS subjects
+//
+// Every room is mapped to a separate subject, direct messages
+// are sent to a subject of the receiver session, which is subscribed
+// only by the hub owning the session (so no queue groups are needed)
+type Adapter interface {
+	session.Adapter
+	session.RoomSubscriber
+	session.RoomFilter
+	session.SessionSubscriber
+	session.Resyncer
+
+	// Health returns error if the adapter is not connected to NATS
+	Health() error
+
+	// OnError sets callback for non-critical adapter errors
+	//
+	// Only one callback allowed, next calls will replace callback
+	OnError(func(err error))
+
+	// Close drains subscriptions and closes the connection
+	Close() error
+}
+
+type adapter struct {
+	conn    *nats.Conn
+	options Options
+
+	subscriptions      map[string]*nats.Subscription
+	subscriptionsMutex sync.Mutex
+
+	handler  func(envelope *session.Envelope)
+	resyncCb func()
+	errorCb  func(err error)
+	cbMutex  sync.RWMutex
+}
+
+// NewAdapter connects to NATS servers and creates Adapter
+//
+// Connection reconnects forever by default, use natsOptions to change it
+func NewAdapter(url string, options Options, natsOptions ...nats.Option) (Adapter, error) {
+	if options.Prefix == "" {
+		options.Prefix = "session"
+	}
+
+	if options.Encoder == nil {
+		options.Encoder = session.NewJSONEnvelopeEncoder()
+	}
+
+	result := &adapter{
+		options:       options,
+		subscriptions: map[string]*nats.Subscription{},
+
+		handler:  func(*session.Envelope) {},
+		resyncCb: func() {},
+		errorCb:  func(error) {},
+	}
+
+	natsOptions = append(
+		[]nats.Option{nats.MaxReconnects(-1)},
+		append(
+			natsOptions,
+
+			// Adapter must not receive messages published by itself
+			nats.NoEcho(),
+			nats.ReconnectHandler(result.handleReconnect),
+			nats.DisconnectErrHandler(result.handleDisconnect),
+		)...,
+	)
+
+	conn, err := nats.Connect(url, natsOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	result.conn = conn
+
+	// Common subject is used for everything not routed to room or session subjects
+	if err := result.subscribe(options.Prefix + ".all"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if options.AllRooms {
+		if err := result.subscribe(options.Prefix + ".room.*"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (a *adapter) Broadcast(envelope *session.Envelope) {
+	content, err := a.options.Encoder.Encode(envelope)
+	if err != nil {
+		a.callErrorCb(err)
+		return
+	}
+
+	if err := a.conn.Publish(a.subject(envelope), content); err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) HandleBroadcast(fn func(envelope *session.Envelope)) {
+	a.cbMutex.Lock()
+	defer a.cbMutex.Unlock()
+
+	a.handler = fn
+}
+
+func (a *adapter) FiltersRooms() bool {
+	return !a.options.AllRooms
+}
+
+func (a *adapter) SubscribeRoom(room string) {
+	if a.options.AllRooms {
+		return
+	}
+
+	if err := a.subscribe(a.roomSubject(room)); err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) UnsubscribeRoom(room string) {
+	if a.options.AllRooms {
+		return
+	}
+
+	if err := a.unsubscribe(a.roomSubject(room)); err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) SubscribeSession(sessionID string) {
+	if err := a.subscribe(a.sessionSubject(sessionID)); err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) UnsubscribeSession(sessionID string) {
+	if err := a.unsubscribe(a.sessionSubject(sessionID)); err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) Health() error {
+	if status := a.conn.Status(); status != nats.CONNECTED {
+		return errors.Newf("nats: adapter is not connected (%s)", statusNames[status])
+	}
+
+	return nil
+}
+
+func (a *adapter) HandleResync(fn func()) {
+	a.cbMutex.Lock()
+	defer a.cbMutex.Unlock()
+
+	a.resyncCb = fn
+}
+
+func (a *adapter) OnError(fn func(err error)) {
+	a.cbMutex.Lock()
+	defer a.cbMutex.Unlock()
+
+	a.errorCb = fn
+}
+
+func (a *adapter) Close() error {
+	// Drain closes the connection after pending messages are handled
+	return a.conn.Drain()
+}
+
+// subject returns subject used to publish the envelope
+func (a *adapter) subject(envelope *session.Envelope) string {
+	switch {
+	case envelope.Type == session.EnvelopeDirect && envelope.Receiver != "":
+		return a.sessionSubject(envelope.Receiver)
+
+	// Broadcasts to several rooms (or with room exclusions)
+	// must be handled as a whole, common subject is used
+	case envelope.Type == session.EnvelopeBroadcast &&
+		len(envelope.Rooms) == 1 && len(envelope.ExceptRooms) == 0:
+		return a.roomSubject(envelope.Rooms[0])
+	}
+
+	return a.options.Prefix + ".all"
+}
+
+func (a *adapter) roomSubject(room string) string {
+	return a.options.Prefix + ".room." + subjectToken(room)
+}
+
+func (a *adapter) sessionSubject(sessionID string) string {
+	return a.options.Prefix + ".session." + subjectToken(sessionID)
+}
+
+func (a *adapter) subscribe(subject string) error {
+	a.subscriptionsMutex.Lock()
+	defer a.subscriptionsMutex.Unlock()
+
+	if a.subscriptions[subject] != nil {
+		return nil
+	}
+
+	subscription, err := a.conn.Subscribe(subject, a.receive)
+	if err != nil {
+		return err
+	}
+
+	a.subscriptions[subject] = subscription
+
+	// Waiting for the server to apply the subscription,
+	// otherwise broadcasts sent right after it may be missed
+	return a.conn.Flush()
+}
+
+func (a *adapter) unsubscribe(subject string) error {
+	a.subscriptionsMutex.Lock()
+	defer a.subscriptionsMutex.Unlock()
+
+	subscription := a.subscriptions[subject]
+	if subscription == nil {
+		return nil
+	}
+
+	delete(a.subscriptions, subject)
+	return subscription.Unsubscribe()
+}
+
+func (a *adapter) receive(msg *nats.Msg) {
+	envelope, err := a.options.Encoder.Decode(msg.Data)
+	if err != nil {
+		a.callErrorCb(err)
+		return
+	}
+
+	a.getHandler()(envelope)
+}
+
+func (a *adapter) handleReconnect(*nats.Conn) {
+	// Presence changes may be lost while disconnected
+	a.callResyncCb()
+}
+
+func (a *adapter) handleDisconnect(_ *nats.Conn, err error) {
+	if err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) getHandler() func(envelope *session.Envelope) {
+	a.cbMutex.RLock()
+	defer a.cbMutex.RUnlock()
+
+	return a.handler
+}
+
+func (a *adapter) callResyncCb() {
+	a.cbMutex.RLock()
+	fn := a.resyncCb
+	a.cbMutex.RUnlock()
+
+	fn()
+}
+
+func (a *adapter) callErrorCb(err error) {
+	a.cbMutex.RLock()
+	fn := a.errorCb
+	a.cbMutex.RUnlock()
+
+	fn(err)
+}
+
+var statusNames = map[nats.Status]string{
+	nats.DISCONNECTED:  "disconnected",
+	nats.CONNECTED:     "connected",
+	nats.CLOSED:        "closed",
+	nats.RECONNECTING:  "reconnecting",
+	nats.CONNECTING:    "connecting",
+	nats.DRAINING_SUBS: "draining subscriptions",
+	nats.DRAINING_PUBS: "draining publications",
+}
+
+// subjectToken encodes value to be safely used as a subject token
+// (NATS subjects can't contain dots, spaces and wildcards)
+func subjectToken(value string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
@@ -0,0 +1,183 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/session"
+	"github.com/foundation-framework/foundation/session/adaptertest"
+	"github.com/foundation-framework/foundation/session/nats"
+	natsgo "github.com/nats-io/nats.go"
+)
+
+func TestAdapter(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T, n int) []session.Adapter {
+		server := newStubServer(t)
+
+		adapters := make([]session.Adapter, n)
+		for i := range adapters {
+			adapters[i] = newAdapter(t, server)
+		}
+
+		return adapters
+	})
+}
+
+func TestAdapterAllRooms(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T, n int) []session.Adapter {
+		server := newStubServer(t)
+
+		adapters := make([]session.Adapter, n)
+		for i := range adapters {
+			adapters[i] = newAdapterWithOptions(t, server, nats.Options{Prefix: "test", AllRooms: true})
+		}
+
+		return adapters
+	})
+}
+
+func TestAdapterReconnect(t *testing.T) {
+	server := newStubServer(t)
+
+	sender := newAdapter(t, server)
+	receiver := newAdapter(t, server)
+
+	if err := receiver.Health(); err != nil {
+		t.Fatal(err)
+	}
+
+	server.stop()
+	waitHealth(t, receiver, false)
+
+	server.restart(t)
+	waitHealth(t, sender, true)
+	waitHealth(t, receiver, true)
+
+	// Subscriptions are restored after reconnect
+	received := make(chan *session.Envelope, 16)
+	receiver.HandleBroadcast(func(envelope *session.Envelope) {
+		if envelope.Type == session.EnvelopeBroadcast {
+			received <- envelope
+		}
+	})
+
+	receiver.SubscribeRoom("room")
+
+	// Subscription is sent asynchronously, flushing it by the round trip
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		sender.Broadcast(&session.Envelope{
+			Type:  session.EnvelopeBroadcast,
+			Rooms: []string{"room"},
+			Topic: "message",
+		})
+
+		select {
+		case envelope := <-received:
+			if envelope.Topic != "message" {
+				t.Fatalf("unexpected topic %s", envelope.Topic)
+			}
+
+			return
+		case <-time.After(time.Millisecond * 100):
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("broadcast is not received after reconnect")
+		}
+	}
+}
+
+func TestAdapterHealthAfterClose(t *testing.T) {
+	server := newStubServer(t)
+
+	adapter, err := nats.NewAdapter(server.url(), nats.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := adapter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitHealth(t, adapter, false)
+}
+
+func TestAdapterHistory(t *testing.T) {
+	server := newStubServer(t)
+
+	filtering := session.NewHub(newAdapter(t, server))
+	defer filtering.Close()
+
+	if err := filtering.SetHistory(session.NewMemoryHistory(10, time.Minute)); !errors.Is(err, session.ErrRoomsFiltered) {
+		t.Fatalf("expected ErrRoomsFiltered, got %v", err)
+	}
+
+	recorder := session.NewHub(newAdapterWithOptions(t, server, nats.Options{Prefix: "test", AllRooms: true}))
+	defer recorder.Close()
+
+	if err := recorder.SetHistory(session.NewMemoryHistory(10, time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := session.NewHub(newAdapter(t, server))
+	defer sender.Close()
+
+	// Recorder has no sessions in the room, but records its history
+	if err := sender.To("room").Emit("topic", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		entries, err := recorder.History("room", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entries) == 1 && entries[0].Topic == "topic" {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 1 history entry, got %d", len(entries))
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func newAdapter(t *testing.T, server *stubServer) nats.Adapter {
+	return newAdapterWithOptions(t, server, nats.Options{Prefix: "test"})
+}
+
+func newAdapterWithOptions(t *testing.T, server *stubServer, options nats.Options) nats.Adapter {
+	adapter, err := nats.NewAdapter(
+		server.url(),
+		options,
+		natsgo.ReconnectWait(time.Millisecond*50),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = adapter.Close() })
+	return adapter
+}
+
+// waitHealth waits for the adapter to become healthy or unhealthy
+func waitHealth(t *testing.T, adapter nats.Adapter, healthy bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	for (adapter.Health() == nil) != healthy {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("adapter health is not changed: %v", adapter.Health())
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package nats_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubServer is an in-process server implementing the subset of NATS
+// client protocol used by the adapter (only "*" wildcard, no headers)
+type stubServer struct {
+	addr string
+
+	listener net.Listener
+	clients  map[*stubClient]struct{}
+	mutex    sync.Mutex
+}
+
+type stubClient struct {
+	conn net.Conn
+	echo bool
+
+	// Subscriptions by sid
+	subscriptions map[string]*stubSubscription
+	writeMutex    sync.Mutex
+}
+
+type stubSubscription struct {
+	client  *stubClient
+	subject string
+	sid     string
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	server := &stubServer{clients: map[*stubClient]struct{}{}}
+	server.start(t, "127.0.0.1:0")
+
+	t.Cleanup(server.stop)
+	return server
+}
+
+func (s *stubServer) url() string {
+	return "nats://" + s.addr
+}
+
+// start starts listening, the previous address is used after stop
+func (s *stubServer) start(t *testing.T, addr string) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mutex.Lock()
+	s.listener = listener
+	s.addr = listener.Addr().String()
+	s.mutex.Unlock()
+
+	go s.acceptLoop(listener)
+}
+
+func (s *stubServer) restart(t *testing.T) {
+	s.start(t, s.addr)
+}
+
+// stop closes the listener and all client connections
+func (s *stubServer) stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_ = s.listener.Close()
+
+	for client := range s.clients {
+		_ = client.conn.Close()
+	}
+
+	s.clients = map[*stubClient]struct{}{}
+}
+
+func (s *stubServer) acceptLoop(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		client := &stubClient{
+			conn:          conn,
+			echo:          true,
+			subscriptions: map[string]*stubSubscription{},
+		}
+
+		s.mutex.Lock()
+		s.clients[client] = struct{}{}
+		s.mutex.Unlock()
+
+		go s.serve(client)
+	}
+}
+
+func (s *stubServer) serve(client *stubClient) {
+	defer func() {
+		s.mutex.Lock()
+		delete(s.clients, client)
+		s.mutex.Unlock()
+
+		_ = client.conn.Close()
+	}()
+
+	host, port, _ := net.SplitHostPort(s.addr)
+	client.write(fmt.Sprintf(
+		"INFO {\"server_id\":\"stub\",\"version\":\"2.0.0\",\"proto\":1,"+
+			"\"host\":\"%s\",\"port\":%s,\"max_payload\":1048576}\r\n",
+		host, port,
+	))
+
+	reader := bufio.NewReader(client.conn)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "CONNECT":
+			var options struct {
+				Echo *bool `json:"echo"`
+			}
+
+			_ = json.Unmarshal([]byte(strings.TrimSpace(line[len("CONNECT"):])), &options)
+			if options.Echo != nil {
+				client.echo = *options.Echo
+			}
+
+		case "PING":
+			client.write("PONG\r\n")
+
+		case "SUB":
+			subscription := &stubSubscription{
+				client:  client,
+				subject: fields[1],
+				sid:     fields[len(fields)-1],
+			}
+
+			s.mutex.Lock()
+			client.subscriptions[subscription.sid] = subscription
+			s.mutex.Unlock()
+
+		case "UNSUB":
+			s.mutex.Lock()
+			delete(client.subscriptions, fields[1])
+			s.mutex.Unlock()
+
+		case "PUB":
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return
+			}
+
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+
+			s.publish(client, fields[1], payload[:size])
+		}
+	}
+}
+
+func (s *stubServer) publish(publisher *stubClient, subject string, payload []byte) {
+	s.mutex.Lock()
+
+	receivers := []*stubSubscription{}
+
+	for client := range s.clients {
+		if client == publisher && !client.echo {
+			continue
+		}
+
+		for _, subscription := range client.subscriptions {
+			if !subjectMatches(subscription.subject, subject) {
+				continue
+			}
+
+			receivers = append(receivers, subscription)
+		}
+	}
+
+	s.mutex.Unlock()
+
+	for _, subscription := range receivers {
+		subscription.client.write(fmt.Sprintf(
+			"MSG %s %s %d\r\n%s\r\n",
+			subject, subscription.sid, len(payload), payload,
+		))
+	}
+}
+
+func (c *stubClient) write(content string) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	_, _ = c.conn.Write([]byte(content))
+}
+
+// subjectMatches reports whether the subject matches the subscription subject,
+// "*" token matches any single token
+func subjectMatches(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+
+	if len(patternTokens) != len(subjectTokens) {
+		return false
+	}
+
+	for i, token := range patternTokens {
+		if token != "*" && token != subjectTokens[i] {
+			return false
+		}
+	}
+
+	return true
+}
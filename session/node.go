@@ -0,0 +1,122 @@
+package session
+
+import (
+	"sync"
+	"time"
+
+	"github.com/foundation-framework/foundation/rand"
+)
+
+// dedupWindow is a number of last received envelope ids remembered by hub
+const dedupWindow = 4096
+
+// LatencyObserver is implemented by adapters interested in the delivery
+// latency of envelopes received from other hubs
+//
+// Latency is measured with the sender's clock, so clocks of all nodes
+// should be synchronized for it to be meaningful
+type LatencyObserver interface {
+	ObserveLatency(node string, latency time.Duration)
+}
+
+// NodeID returns unique identifier of the hub, envelopes are marked with it
+// to ignore the ones sent by the hub itself
+func (p *Hub) NodeID() string {
+	return p.nodeID
+}
+
+// OnLatency sets callback for the delivery latency of envelopes
+// received from other hubs (see LatencyObserver)
+//
+// Multiple callback allowed
+func (p *Hub) OnLatency(fn func(node string, latency time.Duration)) {
+	p.callbacksMutex.Lock()
+	defer p.callbacksMutex.Unlock()
+
+	p.latencyCb = append(p.latencyCb, fn)
+}
+
+// stamp returns copy of the envelope with delivery metadata,
+// id already assigned to the envelope is kept
+func (p *Hub) stamp(envelope *Envelope) *Envelope {
+	stamped := *envelope
+	stamped.Node = p.nodeID
+	stamped.Time = time.Now().UnixNano()
+
+	if stamped.ID == "" {
+		stamped.ID = rand.UUID()
+	}
+
+	return &stamped
+}
+
+// accept reports whether the envelope received from the adapter should be handled
+func (p *Hub) accept(adapter Adapter, envelope *Envelope) bool {
+	// Adapter echoed envelope sent by this hub
+	if envelope.Node == p.nodeID {
+		return false
+	}
+
+	// Envelope was already received, e.g. through another adapter
+	if envelope.ID != "" && !p.seen.add(envelope.ID) {
+		return false
+	}
+
+	if envelope.Time != 0 {
+		latency := time.Since(time.Unix(0, envelope.Time))
+
+		if observer, ok := adapter.(LatencyObserver); ok {
+			observer.ObserveLatency(envelope.Node, latency)
+		}
+
+		p.callLatencyCb(envelope.Node, latency)
+	}
+
+	return true
+}
+
+func (p *Hub) callLatencyCb(node string, latency time.Duration) {
+	p.callbacksMutex.RLock()
+	callbacks := p.latencyCb
+	p.callbacksMutex.RUnlock()
+
+	for _, fn := range callbacks {
+		fn(node, latency)
+	}
+}
+
+// idSet is a set of ids bounded by the number of last added ones
+type idSet struct {
+	ids   map[string]struct{}
+	order []string
+	next  int
+	mutex sync.Mutex
+}
+
+func newIDSet(limit int) *idSet {
+	return &idSet{
+		ids:   make(map[string]struct{}, limit),
+		order: make([]string, limit),
+	}
+}
+
+// add reports whether the id was not in the set
+func (s *idSet) add(id string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+
+	// Forgetting the oldest id
+	if oldest := s.order[s.next]; oldest != "" {
+		delete(s.ids, oldest)
+	}
+
+	s.ids[id] = struct{}{}
+	s.order[s.next] = id
+	s.next = (s.next + 1) % len(s.order)
+
+	return true
+}
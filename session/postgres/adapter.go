@@ -0,0 +1,324 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/rand"
+	"github.com/foundation-framework/foundation/session"
+	"github.com/lib/pq"
+)
+
+const (
+	// originSize is a size of origin prefix of every notification
+	originSize = 32
+
+	// notifyLimit is a maximal size of NOTIFY payload (8000 bytes by default)
+	notifyLimit = 7900
+
+	inlineMarker = ':'
+	spillMarker  = '@'
+)
+
+var (
+	// spillRetention is a time spilled payloads are kept for other nodes
+	spillRetention = time.Minute
+
+	// spillCleanup limits frequency of removing expired spilled payloads
+	spillCleanup = time.Second * 10
+
+	errMalformedNotification = errors.New("postgres: malformed adapter notification")
+)
+
+// Options describes PostgreSQL adapter options
+type Options struct {
+	// Channel is a name of LISTEN/NOTIFY channel ("session" used if empty)
+	//
+	// Payloads too large for NOTIFY are stored in "<channel>_payloads" unlogged table
+	Channel string
+
+	// Encoder is used to serialize envelopes (JSON used if nil)
+	Encoder session.EnvelopeEncoder
+
+	// MinReconnect and MaxReconnect limit listener reconnection intervals
+	// (10 milliseconds and 1 minute used if empty)
+	MinReconnect time.Duration
+	MaxReconnect time.Duration
+}
+
+// Adapter is session.Adapter based on PostgreSQL LISTEN/NOTIFY
+type Adapter interface {
+	session.Adapter
+	session.Resyncer
+
+	// Health returns error if the adapter is not connected to PostgreSQL
+	Health() error
+
+	// OnError sets callback for non-critical adapter errors
+	//
+	// Only one callback allowed, next calls will replace callback
+	OnError(func(err error))
+
+	// Close stops listening, the database is not closed by the adapter
+	Close() error
+}
+
+type adapter struct {
+	db       *sql.DB
+	listener *pq.Listener
+	options  Options
+	table    string
+
+	// Origin is used to skip notifications sent by the adapter itself
+	origin string
+
+	lastCleanup time.Time
+	spillMutex  sync.Mutex
+
+	handler  func(envelope *session.Envelope)
+	resyncCb func()
+	errorCb  func(err error)
+	cbMutex  sync.RWMutex
+}
+
+// NewAdapter creates Adapter, db is used to send notifications
+// and dsn is used to open a separate listening connection
+func NewAdapter(db *sql.DB, dsn string, options Options) (Adapter, error) {
+	if options.Channel == "" {
+		options.Channel = "session"
+	}
+
+	if options.Encoder == nil {
+		options.Encoder = session.NewJSONEnvelopeEncoder()
+	}
+
+	if options.MinReconnect == 0 {
+		options.MinReconnect = time.Millisecond * 10
+	}
+
+	if options.MaxReconnect == 0 {
+		options.MaxReconnect = time.Minute
+	}
+
+	result := &adapter{
+		db:      db,
+		options: options,
+		table:   pq.QuoteIdentifier(options.Channel + "_payloads"),
+		origin:  rand.Hex(originSize / 2),
+
+		handler:  func(*session.Envelope) {},
+		resyncCb: func() {},
+		errorCb:  func(error) {},
+	}
+
+	_, err := db.Exec(
+		"CREATE UNLOGGED TABLE IF NOT EXISTS " + result.table + " (" +
+			"id BIGSERIAL PRIMARY KEY, " +
+			"payload BYTEA NOT NULL, " +
+			"created_at TIMESTAMPTZ NOT NULL DEFAULT now()" +
+			")",
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result.listener = pq.NewListener(
+		dsn,
+		options.MinReconnect,
+		options.MaxReconnect,
+		result.handleEvent,
+	)
+
+	if err := result.listener.Listen(options.Channel); err != nil {
+		_ = result.listener.Close()
+		return nil, err
+	}
+
+	go func() {
+		result.receiveLoop()
+	}()
+
+	return result, nil
+}
+
+func (a *adapter) Broadcast(envelope *session.Envelope) {
+	content, err := a.options.Encoder.Encode(envelope)
+	if err != nil {
+		a.callErrorCb(err)
+		return
+	}
+
+	// Payload is always encoded, NOTIFY accepts only text
+	payload := a.origin + string(inlineMarker) + base64.StdEncoding.EncodeToString(content)
+
+	if len(payload) > notifyLimit {
+		id, err := a.spill(content)
+		if err != nil {
+			a.callErrorCb(err)
+			return
+		}
+
+		payload = a.origin + string(spillMarker) + strconv.FormatInt(id, 10)
+	}
+
+	if _, err := a.db.Exec("SELECT pg_notify($1, $2)", a.options.Channel, payload); err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) HandleBroadcast(fn func(envelope *session.Envelope)) {
+	a.cbMutex.Lock()
+	defer a.cbMutex.Unlock()
+
+	a.handler = fn
+}
+
+func (a *adapter) Health() error {
+	return a.listener.Ping()
+}
+
+func (a *adapter) HandleResync(fn func()) {
+	a.cbMutex.Lock()
+	defer a.cbMutex.Unlock()
+
+	a.resyncCb = fn
+}
+
+func (a *adapter) OnError(fn func(err error)) {
+	a.cbMutex.Lock()
+	defer a.cbMutex.Unlock()
+
+	a.errorCb = fn
+}
+
+func (a *adapter) Close() error {
+	return a.listener.Close()
+}
+
+// spill stores payload too large for NOTIFY and returns its id
+func (a *adapter) spill(content []byte) (int64, error) {
+	a.cleanup()
+
+	var id int64
+	err := a.db.QueryRow(
+		"INSERT INTO "+a.table+" (payload) VALUES ($1) RETURNING id",
+		content,
+	).Scan(&id)
+
+	return id, err
+}
+
+// cleanup removes spilled payloads already received by other nodes
+func (a *adapter) cleanup() {
+	a.spillMutex.Lock()
+	if time.Since(a.lastCleanup) < spillCleanup {
+		a.spillMutex.Unlock()
+		return
+	}
+
+	a.lastCleanup = time.Now()
+	a.spillMutex.Unlock()
+
+	_, err := a.db.Exec(
+		"DELETE FROM "+a.table+" WHERE created_at < $1",
+		time.Now().Add(-spillRetention),
+	)
+
+	if err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) receiveLoop() {
+	for notification := range a.listener.Notify {
+		if notification == nil {
+			// Connection was re-established, notifications may be lost
+			a.callResyncCb()
+			continue
+		}
+
+		envelope, err := a.decode(notification.Extra)
+		if err != nil {
+			a.callErrorCb(err)
+			continue
+		}
+
+		if envelope != nil {
+			a.getHandler()(envelope)
+		}
+	}
+}
+
+// decode decodes notification payload (nil envelope returned for own notifications)
+func (a *adapter) decode(payload string) (*session.Envelope, error) {
+	if len(payload) <= originSize {
+		return nil, errMalformedNotification
+	}
+
+	if payload[:originSize] == a.origin {
+		return nil, nil
+	}
+
+	var content []byte
+	var err error
+
+	switch payload[originSize] {
+	case inlineMarker:
+		content, err = base64.StdEncoding.DecodeString(payload[originSize+1:])
+	case spillMarker:
+		content, err = a.load(payload[originSize+1:])
+	default:
+		err = errMalformedNotification
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return a.options.Encoder.Decode(content)
+}
+
+// load loads spilled payload
+func (a *adapter) load(id string) ([]byte, error) {
+	var content []byte
+	err := a.db.QueryRow(
+		"SELECT payload FROM "+a.table+" WHERE id = $1",
+		id,
+	).Scan(&content)
+
+	return content, err
+}
+
+func (a *adapter) handleEvent(_ pq.ListenerEventType, err error) {
+	if err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) getHandler() func(envelope *session.Envelope) {
+	a.cbMutex.RLock()
+	defer a.cbMutex.RUnlock()
+
+	return a.handler
+}
+
+func (a *adapter) callResyncCb() {
+	a.cbMutex.RLock()
+	fn := a.resyncCb
+	a.cbMutex.RUnlock()
+
+	fn()
+}
+
+func (a *adapter) callErrorCb(err error) {
+	a.cbMutex.RLock()
+	fn := a.errorCb
+	a.cbMutex.RUnlock()
+
+	fn(err)
+}
@@ -0,0 +1,147 @@
+package postgres_test
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/foundation-framework/foundation/rand"
+	"github.com/foundation-framework/foundation/session"
+	"github.com/foundation-framework/foundation/session/adaptertest"
+	"github.com/foundation-framework/foundation/session/postgres"
+	"github.com/lib/pq"
+)
+
+func TestAdapter(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T, n int) []session.Adapter {
+		adapters := newAdapters(t, openDB(t), newChannel(), n)
+
+		result := make([]session.Adapter, n)
+		for i, adapter := range adapters {
+			result[i] = adapter
+		}
+
+		return result
+	})
+}
+
+func TestAdapterSpill(t *testing.T) {
+	adapters := newAdapters(t, openDB(t), newChannel(), 2)
+	sender, receiver := adapters[0], adapters[1]
+
+	errs := make(chan error, 4)
+	sender.OnError(func(err error) { errs <- err })
+	receiver.OnError(func(err error) { errs <- err })
+
+	received := make(chan *session.Envelope, 1)
+	receiver.HandleBroadcast(func(envelope *session.Envelope) {
+		received <- envelope
+	})
+
+	// Payload is larger than NOTIFY allows
+	payload := bytes.Repeat([]byte("payload"), 2000)
+
+	sender.Broadcast(&session.Envelope{
+		Type:    session.EnvelopeBroadcast,
+		Rooms:   []string{"room"},
+		Topic:   "topic",
+		Payload: payload,
+	})
+
+	select {
+	case envelope := <-received:
+		if envelope.Topic != "topic" || !bytes.Equal(envelope.Payload, payload) {
+			t.Fatal("spilled payload is corrupted")
+		}
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("spilled broadcast is lost")
+	}
+}
+
+func TestAdapterResync(t *testing.T) {
+	db := openDB(t)
+	channel := newChannel()
+	adapter := newAdapters(t, db, channel, 1)[0]
+
+	resynced := make(chan struct{}, 1)
+	adapter.HandleResync(func() {
+		select {
+		case resynced <- struct{}{}:
+		default:
+		}
+	})
+
+	// Breaking the listening connection, the adapter reconnects
+	_, err := db.Exec(
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity "+
+			"WHERE pid <> pg_backend_pid() AND query = $1",
+		"LISTEN "+pq.QuoteIdentifier(channel),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-resynced:
+	case <-time.After(time.Second * 5):
+		t.Fatal("adapter is not resynced after reconnect")
+	}
+
+	if err := adapter.Health(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newAdapters creates adapters listening to the channel
+// (POSTGRES_DSN is used for listening connections)
+func newAdapters(t *testing.T, db *sql.DB, channel string, n int) []postgres.Adapter {
+	t.Cleanup(func() {
+		_, _ = db.Exec("DROP TABLE IF EXISTS " + pq.QuoteIdentifier(channel+"_payloads"))
+	})
+
+	adapters := make([]postgres.Adapter, n)
+	for i := range adapters {
+		adapter, err := postgres.NewAdapter(db, os.Getenv("POSTGRES_DSN"), postgres.Options{Channel: channel})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() { _ = adapter.Close() })
+
+		adapters[i] = adapter
+	}
+
+	return adapters
+}
+
+// newChannel returns unique channel name, so every test uses its own channel
+// (test names are too long for PostgreSQL identifiers)
+func newChannel() string {
+	return "session_" + rand.Hex(8)
+}
+
+// openDB connects to PostgreSQL from POSTGRES_DSN or skips the test when it is not set
+func openDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err := db.Ping(); err != nil {
+		t.Skipf("postgres is not available: %v", err)
+	}
+
+	return db
+}
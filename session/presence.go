@@ -0,0 +1,141 @@
+package session
+
+import (
+	"sort"
+)
+
+// PresenceType describes presence change
+type PresenceType int
+
+const (
+	PresenceJoin PresenceType = iota
+	PresenceLeave
+)
+
+// PresenceEvent represents a session joining or leaving the room
+type PresenceEvent struct {
+	Type      PresenceType
+	Room      string
+	SessionID string
+
+	// Remote reports whether the session is connected to another hub
+	Remote bool
+}
+
+// OnPresence sets callback for presence changes of local
+// and remote (shared through adapters) sessions
+//
+// Multiple callback allowed
+func (p *Hub) OnPresence(fn func(event PresenceEvent)) {
+	p.callbacksMutex.Lock()
+	defer p.callbacksMutex.Unlock()
+
+	p.presenceCb = append(p.presenceCb, fn)
+}
+
+// Presence returns sorted ids of sessions in the room
+// including sessions connected to other hubs
+//
+// Remote sessions are known from presence announced since the hub creation
+func (p *Hub) Presence(room string) []string {
+	ids := []string{}
+
+	for _, session := range p.rooms.members(room) {
+		ids = append(ids, session.ID())
+	}
+
+	ids = append(ids, p.remoteMembers(room)...)
+
+	sort.Strings(ids)
+	return ids
+}
+
+// resync announces local sessions and asks other hubs
+// for their presence, which may be lost by adapters
+func (p *Hub) resync() {
+	p.announce()
+	p.broadcastAdapters(&Envelope{Type: EnvelopeSync})
+}
+
+// announce shares all local sessions and their presence through adapters
+func (p *Hub) announce() {
+	envelopes := []*Envelope{}
+
+	p.sessionsMutex.RLock()
+	for id := range p.sessions {
+		envelopes = append(envelopes, &Envelope{
+			Type:      EnvelopeRegister,
+			SessionID: id,
+		})
+	}
+	p.sessionsMutex.RUnlock()
+
+	p.usersMutex.RLock()
+	for session, userID := range p.sessionUsers {
+		envelopes = append(envelopes, &Envelope{
+			Type:      EnvelopeBind,
+			SessionID: session.ID(),
+			User:      userID,
+		})
+	}
+	p.usersMutex.RUnlock()
+
+	for room, sessions := range p.rooms.snapshot() {
+		for _, session := range sessions {
+			envelopes = append(envelopes, &Envelope{
+				Type:      EnvelopeJoin,
+				SessionID: session.ID(),
+				Room:      room,
+			})
+		}
+	}
+
+	p.roomInfoMutex.RLock()
+	for room, info := range p.roomInfo {
+		if info.UpdatedAt.IsZero() {
+			// Metadata created locally is never shared
+			continue
+		}
+
+		envelopes = append(envelopes, &Envelope{
+			Type:     EnvelopeRoom,
+			Room:     room,
+			RoomInfo: info.clone(),
+		})
+	}
+	p.roomInfoMutex.RUnlock()
+
+	for _, envelope := range envelopes {
+		p.broadcastAdapters(envelope)
+	}
+}
+
+// changePresence shares local presence change and notifies observers
+func (p *Hub) changePresence(presenceType PresenceType, sessionID, room string) {
+	envelopeType := EnvelopeJoin
+	if presenceType == PresenceLeave {
+		envelopeType = EnvelopeLeave
+	}
+
+	p.broadcastAdapters(&Envelope{
+		Type:      envelopeType,
+		SessionID: sessionID,
+		Room:      room,
+	})
+
+	p.callPresenceCb(PresenceEvent{
+		Type:      presenceType,
+		Room:      room,
+		SessionID: sessionID,
+	})
+}
+
+func (p *Hub) callPresenceCb(event PresenceEvent) {
+	p.callbacksMutex.RLock()
+	callbacks := p.presenceCb
+	p.callbacksMutex.RUnlock()
+
+	for _, fn := range callbacks {
+		fn(event)
+	}
+}
@@ -0,0 +1,306 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+	"github.com/foundation-framework/foundation/rand"
+	"github.com/foundation-framework/foundation/session"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// originSize is a size of origin prefix of every published message
+	originSize = 32
+
+	// subscribeTimeout limits waiting for subscription confirmation
+	subscribeTimeout = time.Second * 5
+)
+
+var (
+	errMalformedMessage = errors.New("redis: malformed adapter message")
+	errSubscribeTimeout = errors.New("redis: subscription is not confirmed in time")
+)
+
+// Options describes redis adapter options
+type Options struct {
+	// Prefix is a prefix of redis channels ("session" used if empty)
+	Prefix string
+
+	// PerRoom makes adapter subscribe to a separate channel for every local room,
+	// so the node doesn't receive broadcasts to rooms without local sessions
+	// (hub history can't be used in this mode, see session.Hub.SetHistory)
+	PerRoom bool
+
+	// Encoder is used to serialize envelopes (JSON used if nil)
+	Encoder session.EnvelopeEncoder
+}
+
+// Adapter is session.Adapter based on redis pub/sub
+type Adapter interface {
+	session.Adapter
+	session.RoomSubscriber
+	session.RoomFilter
+
+	// OnError sets callback for non-critical adapter errors
+	//
+	// Only one callback allowed, next calls will replace callback
+	OnError(func(err error))
+
+	// Close unsubscribes from all channels
+	Close() error
+}
+
+type adapter struct {
+	client  goredis.UniversalClient
+	pubsub  *goredis.PubSub
+	options Options
+
+	// Origin is used to skip messages published by the adapter itself
+	origin []byte
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Channels waiting for subscription confirmation
+	pending      map[string][]chan struct{}
+	pendingMutex sync.Mutex
+
+	handler func(envelope *session.Envelope)
+	errorCb func(err error)
+	cbMutex sync.RWMutex
+}
+
+// NewAdapter creates Adapter, the client is not closed by the adapter
+func NewAdapter(client goredis.UniversalClient, options Options) Adapter {
+	if options.Prefix == "" {
+		options.Prefix = "session"
+	}
+
+	if options.Encoder == nil {
+		options.Encoder = session.NewJSONEnvelopeEncoder()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result := &adapter{
+		client:  client,
+		options: options,
+		origin:  []byte(rand.Hex(originSize / 2)),
+
+		ctx:    ctx,
+		cancel: cancel,
+
+		pending: map[string][]chan struct{}{},
+
+		handler: func(*session.Envelope) {},
+		errorCb: func(error) {},
+	}
+
+	result.pubsub = client.Subscribe(ctx)
+	messages := result.pubsub.ChannelWithSubscriptions()
+
+	go func() {
+		result.receiveLoop(messages)
+	}()
+
+	// Common channel is used for everything not routed to room channels
+	if err := result.subscribe(options.Prefix); err != nil {
+		result.callErrorCb(err)
+	}
+
+	return result
+}
+
+func (a *adapter) Broadcast(envelope *session.Envelope) {
+	content, err := a.options.Encoder.Encode(envelope)
+	if err != nil {
+		a.callErrorCb(err)
+		return
+	}
+
+	message := make([]byte, 0, len(a.origin)+len(content))
+	message = append(message, a.origin...)
+	message = append(message, content...)
+
+	if err := a.client.Publish(a.ctx, a.channel(envelope), message).Err(); err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) HandleBroadcast(fn func(envelope *session.Envelope)) {
+	a.cbMutex.Lock()
+	defer a.cbMutex.Unlock()
+
+	a.handler = fn
+}
+
+func (a *adapter) FiltersRooms() bool {
+	return a.options.PerRoom
+}
+
+func (a *adapter) SubscribeRoom(room string) {
+	if !a.options.PerRoom {
+		return
+	}
+
+	if err := a.subscribe(a.roomChannel(room)); err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) UnsubscribeRoom(room string) {
+	if !a.options.PerRoom {
+		return
+	}
+
+	if err := a.pubsub.Unsubscribe(a.ctx, a.roomChannel(room)); err != nil {
+		a.callErrorCb(err)
+	}
+}
+
+func (a *adapter) OnError(fn func(err error)) {
+	a.cbMutex.Lock()
+	defer a.cbMutex.Unlock()
+
+	a.errorCb = fn
+}
+
+func (a *adapter) Close() error {
+	a.cancel()
+	return a.pubsub.Close()
+}
+
+// channel returns channel used to publish the envelope
+func (a *adapter) channel(envelope *session.Envelope) string {
+	if !a.options.PerRoom || envelope.Type != session.EnvelopeBroadcast {
+		return a.options.Prefix
+	}
+
+	// Broadcasts to several rooms (or with room exclusions)
+	// must be handled as a whole, common channel is used
+	if len(envelope.Rooms) != 1 || len(envelope.ExceptRooms) != 0 {
+		return a.options.Prefix
+	}
+
+	return a.roomChannel(envelope.Rooms[0])
+}
+
+func (a *adapter) roomChannel(room string) string {
+	return a.options.Prefix + ":room:" + room
+}
+
+// subscribe subscribes to the channel and waits for the server to confirm it,
+// otherwise messages published right after subscription may be missed
+func (a *adapter) subscribe(channel string) error {
+	confirmed := make(chan struct{})
+
+	a.pendingMutex.Lock()
+	a.pending[channel] = append(a.pending[channel], confirmed)
+	a.pendingMutex.Unlock()
+
+	if err := a.pubsub.Subscribe(a.ctx, channel); err != nil {
+		a.forget(channel, confirmed)
+		return err
+	}
+
+	select {
+	case <-confirmed:
+		return nil
+	case <-a.ctx.Done():
+		a.forget(channel, confirmed)
+		return a.ctx.Err()
+	case <-time.After(subscribeTimeout):
+		a.forget(channel, confirmed)
+		return errSubscribeTimeout
+	}
+}
+
+// forget stops waiting for the channel confirmation
+func (a *adapter) forget(channel string, confirmed chan struct{}) {
+	a.pendingMutex.Lock()
+	defer a.pendingMutex.Unlock()
+
+	waiting := a.pending[channel]
+	for i, pending := range waiting {
+		if pending == confirmed {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+
+	if len(waiting) == 0 {
+		delete(a.pending, channel)
+	} else {
+		a.pending[channel] = waiting
+	}
+}
+
+// confirm releases all subscribers waiting for the channel
+func (a *adapter) confirm(channel string) {
+	a.pendingMutex.Lock()
+	waiting := a.pending[channel]
+	delete(a.pending, channel)
+	a.pendingMutex.Unlock()
+
+	for _, confirmed := range waiting {
+		close(confirmed)
+	}
+}
+
+func (a *adapter) receiveLoop(messages <-chan interface{}) {
+	for received := range messages {
+		var message *goredis.Message
+
+		switch received := received.(type) {
+		case *goredis.Subscription:
+			if received.Kind == "subscribe" {
+				a.confirm(received.Channel)
+			}
+
+			continue
+		case *goredis.Message:
+			message = received
+		default:
+			continue
+		}
+
+		payload := []byte(message.Payload)
+
+		if len(payload) < originSize {
+			a.callErrorCb(errMalformedMessage)
+			continue
+		}
+
+		if bytes.Equal(payload[:originSize], a.origin) {
+			// Message published by the adapter itself
+			continue
+		}
+
+		envelope, err := a.options.Encoder.Decode(payload[originSize:])
+		if err != nil {
+			a.callErrorCb(err)
+			continue
+		}
+
+		a.getHandler()(envelope)
+	}
+}
+
+func (a *adapter) getHandler() func(envelope *session.Envelope) {
+	a.cbMutex.RLock()
+	defer a.cbMutex.RUnlock()
+
+	return a.handler
+}
+
+func (a *adapter) callErrorCb(err error) {
+	a.cbMutex.RLock()
+	fn := a.errorCb
+	a.cbMutex.RUnlock()
+
+	fn(err)
+}
@@ -0,0 +1,93 @@
+package redis_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/foundation-framework/foundation/session"
+	"github.com/foundation-framework/foundation/session/adaptertest"
+	"github.com/foundation-framework/foundation/session/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestAdapter(t *testing.T) {
+	adaptertest.Run(t, factory(redis.Options{}))
+}
+
+func TestAdapterPerRoom(t *testing.T) {
+	adaptertest.Run(t, factory(redis.Options{PerRoom: true}))
+}
+
+func TestAdapterSubscribeRoom(t *testing.T) {
+	adapters := factory(redis.Options{PerRoom: true})(t, 2)
+	sender, receiver := adapters[0], adapters[1].(redis.Adapter)
+
+	received := make(chan string, 16)
+	receiver.HandleBroadcast(func(envelope *session.Envelope) {
+		received <- envelope.Rooms[0]
+	})
+
+	// Broadcasts right after subscription must not be lost
+	for i := 0; i < 20; i++ {
+		room := "room-" + strconv.Itoa(i)
+
+		receiver.SubscribeRoom(room)
+		sender.Broadcast(&session.Envelope{
+			Type:  session.EnvelopeBroadcast,
+			Rooms: []string{room},
+		})
+
+		select {
+		case got := <-received:
+			if got != room {
+				t.Fatalf("expected broadcast to %s, got %s", room, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("broadcast to %s is lost", room)
+		}
+	}
+}
+
+// factory creates adapters connected to redis from REDIS_ADDR
+// or to in-process redis stub when it is not set
+func factory(options redis.Options) adaptertest.Factory {
+	return func(t *testing.T, n int) []session.Adapter {
+		client := goredis.NewClient(&goredis.Options{Addr: redisAddr(t)})
+		t.Cleanup(func() { _ = client.Close() })
+
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			t.Skipf("redis is not available: %v", err)
+		}
+
+		// Every test uses its own channels
+		options.Prefix = t.Name()
+
+		adapters := make([]session.Adapter, n)
+		for i := range adapters {
+			adapter := redis.NewAdapter(client, options)
+			t.Cleanup(func() { _ = adapter.Close() })
+
+			adapters[i] = adapter
+		}
+
+		return adapters
+	}
+}
+
+func redisAddr(t *testing.T) string {
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		return addr
+	}
+
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Skipf("redis stub is not available: %v", err)
+	}
+
+	t.Cleanup(server.Close)
+	return server.Addr()
+}
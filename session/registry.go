@@ -0,0 +1,65 @@
+package session
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/foundation-framework/foundation/errors"
+)
+
+// Registry maps topics to models used to decode message data
+// received from other hubs through adapters
+type Registry struct {
+	codec Codec
+
+	models      map[string]reflect.Type
+	modelsMutex sync.RWMutex
+}
+
+func NewRegistry(codec Codec) *Registry {
+	return &Registry{
+		codec:  codec,
+		models: map[string]reflect.Type{},
+	}
+}
+
+// Register registers model for the topic, model must be a pointer
+func (r *Registry) Register(topic string, model interface{}) {
+	modelType := reflect.TypeOf(model)
+	if modelType == nil || modelType.Kind() != reflect.Ptr {
+		errors.Panicf("session: registry model must be a pointer")
+	}
+
+	r.modelsMutex.Lock()
+	defer r.modelsMutex.Unlock()
+
+	r.models[topic] = modelType.Elem()
+}
+
+// Encode encodes message data of the topic
+func (r *Registry) Encode(topic string, data interface{}) ([]byte, error) {
+	content, err := r.codec.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "session: failed to encode \"%s\" data", topic)
+	}
+
+	return content, nil
+}
+
+// Decode decodes message data into a new instance of the topic model
+func (r *Registry) Decode(topic string, content []byte) (interface{}, error) {
+	r.modelsMutex.RLock()
+	modelType := r.models[topic]
+	r.modelsMutex.RUnlock()
+
+	if modelType == nil {
+		return nil, errors.Newf("session: no model registered for \"%s\" topic", topic)
+	}
+
+	model := reflect.New(modelType).Interface()
+	if err := r.codec.Unmarshal(content, model); err != nil {
+		return nil, errors.Wrapf(err, "session: failed to decode \"%s\" data", topic)
+	}
+
+	return model, nil
+}
@@ -0,0 +1,296 @@
+package session
+
+import (
+	"time"
+)
+
+const (
+	// defaultHeartbeat is a default interval of hub heartbeats
+	defaultHeartbeat = time.Second * 5
+
+	// defaultNodeTimeout is a default time after which silent hub is considered gone
+	defaultNodeTimeout = time.Second * 15
+)
+
+// remoteNode represents state of sessions connected to another hub
+type remoteNode struct {
+	seen time.Time
+
+	sessions map[string]struct{}
+	rooms    map[string]map[string]struct{}
+	users    map[string]map[string]struct{}
+}
+
+func newRemoteNode() *remoteNode {
+	return &remoteNode{
+		seen:     time.Now(),
+		sessions: map[string]struct{}{},
+		rooms:    map[string]map[string]struct{}{},
+		users:    map[string]map[string]struct{}{},
+	}
+}
+
+// SetHeartbeat sets interval of heartbeats sent to other hubs and timeout
+// after which silent hub is considered gone and its sessions are forgotten
+//
+// Timeout should be several times longer than interval
+func (p *Hub) SetHeartbeat(interval, timeout time.Duration) {
+	p.heartbeatMutex.Lock()
+	defer p.heartbeatMutex.Unlock()
+
+	p.heartbeat = interval
+	p.nodeTimeout = timeout
+}
+
+// Close stops heartbeats and tells other hubs to forget sessions of this hub
+// (local sessions are not closed)
+func (p *Hub) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+
+		p.broadcastAdapters(&Envelope{Type: EnvelopeShutdown})
+	})
+}
+
+func (p *Hub) heartbeatLoop() {
+	for {
+		p.heartbeatMutex.RLock()
+		interval, timeout := p.heartbeat, p.nodeTimeout
+		p.heartbeatMutex.RUnlock()
+
+		select {
+		case <-p.done:
+			return
+		case <-time.After(interval):
+		}
+
+		p.broadcastAdapters(&Envelope{Type: EnvelopeHeartbeat})
+		p.purgeSilentNodes(timeout)
+	}
+}
+
+// touchNode remembers that the hub is alive, hub seen for the first time
+// is asked to announce its sessions, which may be missed (e.g. after partition)
+func (p *Hub) touchNode(envelope *Envelope) {
+	p.remoteMutex.Lock()
+
+	node, known := p.nodes[envelope.Node]
+	if !known {
+		node = newRemoteNode()
+		p.nodes[envelope.Node] = node
+	}
+
+	node.seen = time.Now()
+	p.remoteMutex.Unlock()
+
+	if !known && envelope.Type != EnvelopeSync && envelope.Type != EnvelopeShutdown {
+		p.broadcastAdapters(&Envelope{
+			Type:     EnvelopeSync,
+			Receiver: envelope.Node,
+		})
+	}
+}
+
+func (p *Hub) purgeSilentNodes(timeout time.Duration) {
+	deadline := time.Now().Add(-timeout)
+
+	p.remoteMutex.RLock()
+	silent := []string{}
+	for id, node := range p.nodes {
+		if node.seen.Before(deadline) {
+			silent = append(silent, id)
+		}
+	}
+	p.remoteMutex.RUnlock()
+
+	for _, id := range silent {
+		p.purgeNode(id)
+	}
+}
+
+// purgeNode forgets all sessions of another hub
+func (p *Hub) purgeNode(id string) {
+	p.remoteMutex.Lock()
+	node := p.nodes[id]
+	delete(p.nodes, id)
+	p.remoteMutex.Unlock()
+
+	if node == nil {
+		return
+	}
+
+	for room, sessions := range node.rooms {
+		for sessionID := range sessions {
+			p.callPresenceCb(PresenceEvent{
+				Type:      PresenceLeave,
+				Room:      room,
+				SessionID: sessionID,
+				Remote:    true,
+			})
+		}
+
+		p.clearRoomInfo(room)
+	}
+}
+
+// changeRemotePresence applies presence change received from adapters
+func (p *Hub) changeRemotePresence(nodeID string, presenceType PresenceType, sessionID, room string) {
+	p.remoteMutex.Lock()
+
+	node := p.remoteNode(nodeID)
+
+	_, exists := node.rooms[room][sessionID]
+	switch {
+	case presenceType == PresenceJoin && !exists:
+		if node.rooms[room] == nil {
+			node.rooms[room] = map[string]struct{}{}
+		}
+
+		node.rooms[room][sessionID] = struct{}{}
+
+	case presenceType == PresenceLeave && exists:
+		delete(node.rooms[room], sessionID)
+
+		if len(node.rooms[room]) == 0 {
+			delete(node.rooms, room)
+		}
+
+	default:
+		// Repeated announcement
+		p.remoteMutex.Unlock()
+		return
+	}
+
+	p.remoteMutex.Unlock()
+
+	p.callPresenceCb(PresenceEvent{
+		Type:      presenceType,
+		Room:      room,
+		SessionID: sessionID,
+		Remote:    true,
+	})
+
+	if presenceType == PresenceLeave {
+		p.clearRoomInfo(room)
+	}
+}
+
+// changeRemoteSession applies registration received from adapters
+func (p *Hub) changeRemoteSession(nodeID, sessionID string, registered bool) {
+	p.remoteMutex.Lock()
+	defer p.remoteMutex.Unlock()
+
+	node := p.remoteNode(nodeID)
+
+	if registered {
+		node.sessions[sessionID] = struct{}{}
+	} else {
+		delete(node.sessions, sessionID)
+	}
+}
+
+// changeRemoteUser applies user binding received from adapters
+func (p *Hub) changeRemoteUser(nodeID, sessionID, userID string, bound bool) {
+	p.remoteMutex.Lock()
+	defer p.remoteMutex.Unlock()
+
+	node := p.remoteNode(nodeID)
+
+	if bound {
+		if node.users[userID] == nil {
+			node.users[userID] = map[string]struct{}{}
+		}
+
+		node.users[userID][sessionID] = struct{}{}
+		return
+	}
+
+	delete(node.users[userID], sessionID)
+
+	if len(node.users[userID]) == 0 {
+		delete(node.users, userID)
+	}
+}
+
+// remoteNode returns state of another hub, creating it if needed
+// (remoteMutex must be locked)
+func (p *Hub) remoteNode(id string) *remoteNode {
+	node := p.nodes[id]
+	if node == nil {
+		node = newRemoteNode()
+		p.nodes[id] = node
+	}
+
+	return node
+}
+
+func (p *Hub) remoteRooms() []string {
+	p.remoteMutex.RLock()
+	defer p.remoteMutex.RUnlock()
+
+	rooms := []string{}
+	for _, node := range p.nodes {
+		for room := range node.rooms {
+			rooms = append(rooms, room)
+		}
+	}
+
+	return rooms
+}
+
+func (p *Hub) remoteMembers(room string) []string {
+	p.remoteMutex.RLock()
+	defer p.remoteMutex.RUnlock()
+
+	ids := []string{}
+	for _, node := range p.nodes {
+		for id := range node.rooms[room] {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func (p *Hub) remoteCount(room string) int {
+	p.remoteMutex.RLock()
+	defer p.remoteMutex.RUnlock()
+
+	count := 0
+	for _, node := range p.nodes {
+		count += len(node.rooms[room])
+	}
+
+	return count
+}
+
+func (p *Hub) isRemoteSession(sessionID string) bool {
+	p.remoteMutex.RLock()
+	defer p.remoteMutex.RUnlock()
+
+	for _, node := range p.nodes {
+		if _, ok := node.sessions[sessionID]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Hub) remoteUserSessions(userID string) []string {
+	p.remoteMutex.RLock()
+	defer p.remoteMutex.RUnlock()
+
+	ids := []string{}
+	for _, node := range p.nodes {
+		for id := range node.users[userID] {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func (p *Hub) isRemoteUser(userID string) bool {
+	return len(p.remoteUserSessions(userID)) > 0
+}
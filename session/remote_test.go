@@ -0,0 +1,84 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type silentSession struct {
+	id string
+}
+
+func (s *silentSession) ID() string                  { return s.id }
+func (s *silentSession) Close(context.Context) error { return nil }
+func (s *silentSession) ServeBroadcast(*Message)     {}
+
+func TestSilentNodePurge(t *testing.T) {
+	network := NewMemoryNetwork()
+
+	observer := NewHub(network.NewAdapter())
+	defer observer.Close()
+	observer.SetHeartbeat(time.Millisecond*20, time.Millisecond*100)
+
+	crashed := NewHub(network.NewAdapter())
+	crashed.SetHeartbeat(time.Millisecond*20, time.Millisecond*100)
+
+	session := &silentSession{id: "session"}
+	crashed.Register(session)
+	crashed.Bind(session, "user")
+	crashed.UpdateRoom("room", func(info *RoomInfo) { info.MaxMembers = 1 })
+
+	if _, err := crashed.Join(session, "room"); err != nil {
+		t.Fatal(err)
+	}
+
+	if observer.Count("room") != 1 || !observer.SendTo("session", "topic", nil) {
+		t.Fatal("remote session is not known")
+	}
+
+	// Stopping heartbeats without shutdown envelope, like the hub crashed
+	close(crashed.done)
+	time.Sleep(time.Millisecond * 300)
+
+	if observer.Count("room") != 0 {
+		t.Fatal("ghost room member")
+	}
+
+	if observer.SendTo("session", "topic", nil) || observer.SendToUser("user", "topic", nil) {
+		t.Fatal("ghost session")
+	}
+
+	if _, ok := observer.Room("room"); ok {
+		t.Fatal("room metadata is not cleared")
+	}
+
+	// Hub is back (e.g. partition healed) and is asked to announce its sessions
+	crashed.broadcastAdapters(&Envelope{Type: EnvelopeHeartbeat})
+
+	if observer.Count("room") != 1 {
+		t.Fatal("remote session is not announced again")
+	}
+}
+
+func TestShutdownPurge(t *testing.T) {
+	network := NewMemoryNetwork()
+
+	observer := NewHub(network.NewAdapter())
+	defer observer.Close()
+
+	closed := NewHub(network.NewAdapter())
+
+	session := &silentSession{id: "session"}
+	closed.Register(session)
+
+	if _, err := closed.Join(session, "room"); err != nil {
+		t.Fatal(err)
+	}
+
+	closed.Close()
+
+	if observer.Count("room") != 0 || observer.SendTo("session", "topic", nil) {
+		t.Fatal("sessions of closed hub are kept")
+	}
+}
@@ -0,0 +1,162 @@
+package session
+
+import (
+	"time"
+
+	"github.com/foundation-framework/foundation/errors"
+)
+
+// ErrRoomFull is returned by Join when the room reached its MaxMembers
+var ErrRoomFull = errors.New("session: room is full")
+
+// RoomInfo represents room metadata shared by all hubs
+//
+// Metadata is created when the room is created or updated and cleared
+// when the last session (including sessions of other hubs) leaves the room
+type RoomInfo struct {
+	Title     string    `json:"title,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// MaxMembers limits a number of sessions in the room, zero means no limit
+	MaxMembers int `json:"max_members,omitempty"`
+
+	// State is arbitrary room state, values must be serializable
+	// by the adapters envelope encoder when adapters are configured
+	State map[string]interface{} `json:"state,omitempty"`
+}
+
+func (i *RoomInfo) clone() *RoomInfo {
+	clone := *i
+	clone.State = make(map[string]interface{}, len(i.State))
+
+	for key, value := range i.State {
+		clone.State[key] = value
+	}
+
+	return &clone
+}
+
+// Room returns snapshot of the room metadata
+func (p *Hub) Room(room string) (RoomInfo, bool) {
+	p.roomInfoMutex.RLock()
+	defer p.roomInfoMutex.RUnlock()
+
+	info, ok := p.roomInfo[room]
+	if !ok {
+		return RoomInfo{}, false
+	}
+
+	return *info.clone(), true
+}
+
+// UpdateRoom changes the room metadata and shares it with other hubs,
+// metadata is created if it does not exist
+//
+// Concurrent updates from different hubs are resolved by UpdatedAt (last write wins)
+func (p *Hub) UpdateRoom(room string, fn func(info *RoomInfo)) {
+	p.roomInfoMutex.Lock()
+
+	info := p.roomInfo[room]
+	if info == nil {
+		info = &RoomInfo{CreatedAt: time.Now()}
+	} else {
+		// Updated copy is stored, snapshots may be read by other goroutines
+		info = info.clone()
+	}
+
+	fn(info)
+	info.UpdatedAt = time.Now()
+
+	p.roomInfo[room] = info
+	shared := info.clone()
+
+	p.roomInfoMutex.Unlock()
+
+	p.broadcastAdapters(&Envelope{
+		Type:     EnvelopeRoom,
+		Room:     room,
+		RoomInfo: shared,
+	})
+}
+
+func (p *Hub) GetRoomData(room, key string) (interface{}, bool) {
+	p.roomInfoMutex.RLock()
+	defer p.roomInfoMutex.RUnlock()
+
+	info, ok := p.roomInfo[room]
+	if !ok {
+		return nil, false
+	}
+
+	value, ok := info.State[key]
+	return value, ok
+}
+
+func (p *Hub) SetRoomData(room, key string, value interface{}) {
+	p.UpdateRoom(room, func(info *RoomInfo) {
+		if info.State == nil {
+			info.State = map[string]interface{}{}
+		}
+
+		info.State[key] = value
+	})
+}
+
+func (p *Hub) DropRoomData(room, key string) {
+	p.UpdateRoom(room, func(info *RoomInfo) {
+		delete(info.State, key)
+	})
+}
+
+// maxMembers returns the room members limit
+func (p *Hub) maxMembers(room string) int {
+	p.roomInfoMutex.RLock()
+	defer p.roomInfoMutex.RUnlock()
+
+	if info, ok := p.roomInfo[room]; ok {
+		return info.MaxMembers
+	}
+
+	return 0
+}
+
+// createRoomInfo creates local metadata of the created room unless it exists
+//
+// Created metadata is not shared and has zero UpdatedAt,
+// so it never overrides metadata received from other hubs
+func (p *Hub) createRoomInfo(room string) {
+	p.roomInfoMutex.Lock()
+	defer p.roomInfoMutex.Unlock()
+
+	if _, exists := p.roomInfo[room]; !exists {
+		p.roomInfo[room] = &RoomInfo{CreatedAt: time.Now()}
+	}
+}
+
+// clearRoomInfo removes the room metadata when there are no sessions in the room
+func (p *Hub) clearRoomInfo(room string) {
+	p.roomInfoMutex.Lock()
+	defer p.roomInfoMutex.Unlock()
+
+	if !p.Exists(room) {
+		delete(p.roomInfo, room)
+	}
+}
+
+// changeRemoteRoomInfo applies metadata received from adapters
+func (p *Hub) changeRemoteRoomInfo(room string, info *RoomInfo) {
+	if info == nil {
+		return
+	}
+
+	p.roomInfoMutex.Lock()
+	defer p.roomInfoMutex.Unlock()
+
+	if current, ok := p.roomInfo[room]; ok && current.UpdatedAt.After(info.UpdatedAt) {
+		return
+	}
+
+	p.roomInfo[room] = info
+}
@@ -0,0 +1,208 @@
+package session
+
+import "sync"
+
+// indexShards is a number of shards of the room index
+const indexShards = 64
+
+// roomIndex is a room membership index sharded by room names and session ids,
+// so joins, leaves and broadcasts of unrelated rooms don't contend for a lock
+//
+// Room shard is always locked before membership shard
+type roomIndex struct {
+	rooms       [indexShards]roomShard
+	memberships [indexShards]membershipShard
+}
+
+type roomShard struct {
+	rooms map[string]map[Session]struct{}
+	mutex sync.RWMutex
+}
+
+type membershipShard struct {
+	memberships map[Session]map[string]struct{}
+	mutex       sync.RWMutex
+}
+
+func newRoomIndex() *roomIndex {
+	index := &roomIndex{}
+
+	for i := range index.rooms {
+		index.rooms[i].rooms = map[string]map[Session]struct{}{}
+		index.memberships[i].memberships = map[Session]map[string]struct{}{}
+	}
+
+	return index
+}
+
+// join reports whether session joined the room and whether the room is created
+func (i *roomIndex) join(session Session, room string) (bool, bool) {
+	shard := i.roomShard(room)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	created := false
+	if shard.rooms[room] == nil {
+		shard.rooms[room] = map[Session]struct{}{}
+		created = true
+	}
+
+	if _, ok := shard.rooms[room][session]; ok {
+		return false, false
+	}
+
+	shard.rooms[room][session] = struct{}{}
+
+	memberships := i.membershipShard(session)
+	memberships.mutex.Lock()
+	defer memberships.mutex.Unlock()
+
+	if memberships.memberships[session] == nil {
+		memberships.memberships[session] = map[string]struct{}{}
+	}
+
+	memberships.memberships[session][room] = struct{}{}
+
+	return true, created
+}
+
+// leave reports whether session left the room and whether the room is empty now
+func (i *roomIndex) leave(session Session, room string) (bool, bool) {
+	shard := i.roomShard(room)
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	if _, ok := shard.rooms[room][session]; !ok {
+		return false, false
+	}
+
+	delete(shard.rooms[room], session)
+
+	memberships := i.membershipShard(session)
+	memberships.mutex.Lock()
+
+	delete(memberships.memberships[session], room)
+	if len(memberships.memberships[session]) == 0 {
+		delete(memberships.memberships, session)
+	}
+
+	memberships.mutex.Unlock()
+
+	if len(shard.rooms[room]) == 0 {
+		delete(shard.rooms, room)
+		return true, true
+	}
+
+	return true, false
+}
+
+// members returns snapshot of sessions in the room
+func (i *roomIndex) members(room string) []Session {
+	shard := i.roomShard(room)
+
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	members := make([]Session, 0, len(shard.rooms[room]))
+	for session := range shard.rooms[room] {
+		members = append(members, session)
+	}
+
+	return members
+}
+
+func (i *roomIndex) count(room string) int {
+	shard := i.roomShard(room)
+
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	return len(shard.rooms[room])
+}
+
+// contains reports whether the session is a member of the room
+func (i *roomIndex) contains(session Session, room string) bool {
+	memberships := i.membershipShard(session)
+
+	memberships.mutex.RLock()
+	defer memberships.mutex.RUnlock()
+
+	_, ok := memberships.memberships[session][room]
+	return ok
+}
+
+// roomsOf returns snapshot of rooms joined by the session
+func (i *roomIndex) roomsOf(session Session) []string {
+	memberships := i.membershipShard(session)
+
+	memberships.mutex.RLock()
+	defer memberships.mutex.RUnlock()
+
+	rooms := make([]string, 0, len(memberships.memberships[session]))
+	for room := range memberships.memberships[session] {
+		rooms = append(rooms, room)
+	}
+
+	return rooms
+}
+
+// names returns names of all non-empty rooms
+func (i *roomIndex) names() []string {
+	names := []string{}
+
+	for n := range i.rooms {
+		shard := &i.rooms[n]
+
+		shard.mutex.RLock()
+		for room := range shard.rooms {
+			names = append(names, room)
+		}
+		shard.mutex.RUnlock()
+	}
+
+	return names
+}
+
+// snapshot returns snapshot of all non-empty rooms and their sessions
+func (i *roomIndex) snapshot() map[string][]Session {
+	rooms := map[string][]Session{}
+
+	for n := range i.rooms {
+		shard := &i.rooms[n]
+
+		shard.mutex.RLock()
+		for room, sessions := range shard.rooms {
+			members := make([]Session, 0, len(sessions))
+			for session := range sessions {
+				members = append(members, session)
+			}
+
+			rooms[room] = members
+		}
+		shard.mutex.RUnlock()
+	}
+
+	return rooms
+}
+
+func (i *roomIndex) roomShard(room string) *roomShard {
+	return &i.rooms[shardOf(room)]
+}
+
+func (i *roomIndex) membershipShard(session Session) *membershipShard {
+	return &i.memberships[shardOf(session.ID())]
+}
+
+// shardOf returns shard of the key using FNV-1a hash
+// (inlined to avoid allocation of hash.Hash on every call)
+func shardOf(key string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+
+	return hash % indexShards
+}
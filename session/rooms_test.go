@@ -0,0 +1,207 @@
+package session
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// benchmarkHub is a part of Hub used by benchmarks
+type benchmarkHub interface {
+	Join(session Session, room string) (bool, error)
+	Leave(session Session, room string) bool
+	emit(room string)
+}
+
+// shardedHub is Hub broadcasting to a snapshot of room members
+type shardedHub struct {
+	*Hub
+}
+
+func (h shardedHub) emit(room string) {
+	_ = h.To(room).Emit("topic", nil)
+}
+
+// mutexHub is a benchmark baseline (the hub before sharding),
+// a single mutex guards rooms and is held while serving broadcasts
+type mutexHub struct {
+	*Hub
+	mutex sync.RWMutex
+}
+
+func (h *mutexHub) Join(session Session, room string) (bool, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.Hub.Join(session, room)
+}
+
+func (h *mutexHub) Leave(session Session, room string) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.Hub.Leave(session, room)
+}
+
+func (h *mutexHub) emit(room string) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	_ = h.To(room).Emit("topic", nil)
+}
+
+// busySession spends some time serving every broadcast (like encoding and writing)
+type busySession struct {
+	silentSession
+	result uint64
+}
+
+func (s *busySession) ServeBroadcast(*Message) {
+	var sum uint64
+	for n := uint64(0); n < benchmarkServeCost; n++ {
+		sum += n * n
+	}
+
+	atomic.StoreUint64(&s.result, sum)
+}
+
+const (
+	benchmarkRooms     = 1024
+	benchmarkSessions  = 8
+	benchmarkServeCost = 1000
+)
+
+func TestRoomIndex(t *testing.T) {
+	index := newRoomIndex()
+
+	first := &silentSession{id: "first"}
+	second := &silentSession{id: "second"}
+
+	if joined, created := index.join(first, "room"); !joined || !created {
+		t.Fatal("first join must create the room")
+	}
+	if joined, created := index.join(second, "room"); !joined || created {
+		t.Fatal("second join must not create the room")
+	}
+	if joined, _ := index.join(second, "room"); joined {
+		t.Fatal("repeated join must be ignored")
+	}
+
+	if !index.contains(first, "room") || index.count("room") != 2 {
+		t.Fatal("room members are not indexed")
+	}
+
+	if left, empty := index.leave(first, "room"); !left || empty {
+		t.Fatal("room with remaining member must not be empty")
+	}
+	if left, empty := index.leave(second, "room"); !left || !empty {
+		t.Fatal("room without members must be empty")
+	}
+
+	if len(index.names()) != 0 || len(index.roomsOf(second)) != 0 {
+		t.Fatal("empty room is still indexed")
+	}
+}
+
+func BenchmarkJoinLeave(b *testing.B) {
+	runHubBenchmark(b, benchmarkJoinLeave)
+}
+
+func BenchmarkBroadcast(b *testing.B) {
+	runHubBenchmark(b, benchmarkBroadcast)
+}
+
+func BenchmarkMixed(b *testing.B) {
+	runHubBenchmark(b, benchmarkMixed)
+}
+
+func runHubBenchmark(b *testing.B, fn func(b *testing.B, hub benchmarkHub)) {
+	b.Run("sharded", func(b *testing.B) {
+		hub := NewHub()
+		defer hub.Close()
+
+		fn(b, shardedHub{Hub: hub})
+	})
+
+	b.Run("mutex", func(b *testing.B) {
+		hub := NewHub()
+		defer hub.Close()
+
+		fn(b, &mutexHub{Hub: hub})
+	})
+}
+
+func benchmarkJoinLeave(b *testing.B, hub benchmarkHub) {
+	var workers int64
+
+	b.RunParallel(func(pb *testing.PB) {
+		// Every worker uses its own session to avoid repeated joins
+		session := &busySession{silentSession: silentSession{id: "session-" + strconv.FormatInt(atomic.AddInt64(&workers, 1), 10)}}
+		rooms := benchmarkRoomNames()
+
+		for n := 0; pb.Next(); n++ {
+			room := rooms[n%len(rooms)]
+
+			_, _ = hub.Join(session, room)
+			hub.Leave(session, room)
+		}
+	})
+}
+
+func benchmarkBroadcast(b *testing.B, hub benchmarkHub) {
+	rooms := populateHub(hub)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for n := 0; pb.Next(); n++ {
+			hub.emit(rooms[n%len(rooms)])
+		}
+	})
+}
+
+// benchmarkMixed broadcasts to populated rooms while sessions join and leave other rooms
+func benchmarkMixed(b *testing.B, hub benchmarkHub) {
+	rooms := populateHub(hub)
+
+	var workers int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		session := &busySession{silentSession: silentSession{id: "mixed-" + strconv.FormatInt(atomic.AddInt64(&workers, 1), 10)}}
+
+		for n := 0; pb.Next(); n++ {
+			room := rooms[n%len(rooms)]
+
+			if n%4 != 0 {
+				hub.emit(room)
+				continue
+			}
+
+			_, _ = hub.Join(session, "joined-"+room)
+			hub.Leave(session, "joined-"+room)
+		}
+	})
+}
+
+// populateHub fills every benchmark room with sessions and returns room names
+func populateHub(hub benchmarkHub) []string {
+	rooms := benchmarkRoomNames()
+
+	for _, room := range rooms {
+		for n := 0; n < benchmarkSessions; n++ {
+			_, _ = hub.Join(&busySession{silentSession: silentSession{id: room + "-" + strconv.Itoa(n)}}, room)
+		}
+	}
+
+	return rooms
+}
+
+func benchmarkRoomNames() []string {
+	rooms := make([]string, benchmarkRooms)
+	for n := range rooms {
+		rooms[n] = "room-" + strconv.Itoa(n)
+	}
+
+	return rooms
+}
@@ -1,6 +1,16 @@
 package session
 
+import "context"
+
 type Session interface {
 	ID() string
-	ServeBroadcast(topic string, data interface{})
+
+	// Close closes the session (used by Hub.DisconnectUser)
+	Close(ctx context.Context) error
+
+	// ServeBroadcast serves message broadcast to one of session rooms
+	// or sent directly to the session (see Hub.SendTo)
+	//
+	// The same message is passed to every receiver of the broadcast
+	ServeBroadcast(msg *Message)
 }
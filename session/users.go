@@ -0,0 +1,168 @@
+package session
+
+import (
+	"context"
+	"sort"
+)
+
+// Bind binds the session to the user, user may have many sessions
+// (e.g. several tabs or devices) on any hub
+//
+// Session is unbound automatically on Unregister
+func (p *Hub) Bind(session Session, userID string) {
+	previous := p.bind(session, userID)
+	if previous == userID {
+		return
+	}
+
+	if previous != "" {
+		p.broadcastAdapters(&Envelope{
+			Type:      EnvelopeUnbind,
+			SessionID: session.ID(),
+			User:      previous,
+		})
+	}
+
+	p.broadcastAdapters(&Envelope{
+		Type:      EnvelopeBind,
+		SessionID: session.ID(),
+		User:      userID,
+	})
+}
+
+// Unbind removes binding of the session to its user
+func (p *Hub) Unbind(session Session) {
+	userID := p.bind(session, "")
+	if userID == "" {
+		return
+	}
+
+	p.broadcastAdapters(&Envelope{
+		Type:      EnvelopeUnbind,
+		SessionID: session.ID(),
+		User:      userID,
+	})
+}
+
+// User returns user bound to the session (empty if not bound)
+func (p *Hub) User(session Session) string {
+	p.usersMutex.RLock()
+	defer p.usersMutex.RUnlock()
+
+	return p.sessionUsers[session]
+}
+
+// UserSessions returns sorted ids of user sessions
+// including sessions connected to other hubs
+func (p *Hub) UserSessions(userID string) []string {
+	ids := []string{}
+
+	for _, session := range p.localUserSessions(userID) {
+		ids = append(ids, session.ID())
+	}
+
+	ids = append(ids, p.remoteUserSessions(userID)...)
+
+	sort.Strings(ids)
+	return ids
+}
+
+// SendToUser sends message to all user sessions and reports whether any session was found
+//
+// Sessions connected to other hubs are reached through adapters
+func (p *Hub) SendToUser(userID, topic string, data interface{}) bool {
+	found := p.serveUser(userID, topic, data)
+
+	if p.isRemoteUser(userID) {
+		p.broadcastAdapters(&Envelope{
+			Type:  EnvelopeDirect,
+			User:  userID,
+			Topic: topic,
+			Data:  data,
+		})
+
+		found = true
+	}
+
+	return found
+}
+
+// DisconnectUser closes all user sessions including sessions connected to other hubs
+func (p *Hub) DisconnectUser(userID string) {
+	p.disconnectUser(userID)
+
+	if p.isRemoteUser(userID) {
+		p.broadcastAdapters(&Envelope{
+			Type: EnvelopeDisconnect,
+			User: userID,
+		})
+	}
+}
+
+// bind binds the session to the user (empty user unbinds)
+// and returns previous user of the session
+func (p *Hub) bind(session Session, userID string) string {
+	p.usersMutex.Lock()
+	defer p.usersMutex.Unlock()
+
+	previous := p.sessionUsers[session]
+	if previous == userID {
+		return previous
+	}
+
+	if previous != "" {
+		delete(p.users[previous], session)
+
+		if len(p.users[previous]) == 0 {
+			delete(p.users, previous)
+		}
+
+		delete(p.sessionUsers, session)
+	}
+
+	if userID != "" {
+		if p.users[userID] == nil {
+			p.users[userID] = map[Session]struct{}{}
+		}
+
+		p.users[userID][session] = struct{}{}
+		p.sessionUsers[session] = userID
+	}
+
+	return previous
+}
+
+func (p *Hub) localUserSessions(userID string) []Session {
+	p.usersMutex.RLock()
+	defer p.usersMutex.RUnlock()
+
+	sessions := make([]Session, 0, len(p.users[userID]))
+	for session := range p.users[userID] {
+		sessions = append(sessions, session)
+	}
+
+	return sessions
+}
+
+// serveUser serves message to local user sessions and reports whether any session was found
+func (p *Hub) serveUser(userID, topic string, data interface{}) bool {
+	sessions := p.localUserSessions(userID)
+	if len(sessions) == 0 {
+		return false
+	}
+
+	msg := NewMessage(topic, data)
+	for _, session := range sessions {
+		session.ServeBroadcast(msg)
+	}
+
+	return true
+}
+
+func (p *Hub) disconnectUser(userID string) {
+	// Sessions are closed outside the lock, closure unbinds them
+	for _, session := range p.localUserSessions(userID) {
+		// Ignoring any errors
+		_ = session.Close(context.Background())
+	}
+}